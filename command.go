@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kano-lab/aiwolf-nlp-server/core"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/util"
+)
+
+const defaultConfigPath = "./default.yml"
+
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "ゲームサーバを起動します", runServe},
+		{"analyze", "マッチ履歴とログを解析し、レポートを出力します", runAnalyze},
+		{"reduce", "ソースで終了したマッチをデスティネーションのスケジュールから削除します", runReduce},
+		{"validate-config", "設定ファイルを検証します", runValidateConfig},
+		{"schedule", "マッチのスケジュールを生成して出力します", runSchedule},
+		{"replay", "分析サービスのログからゲームの進行を再生します", runReplay},
+		{"convert-logs", "分析サービスのログを従来形式のログに変換します", runConvertLogs},
+		{"version", "バージョンを表示します", func(args []string) error {
+			printVersion()
+			return nil
+		}},
+		{"help", "ヘルプを表示します", func(args []string) error {
+			usage()
+			return nil
+		}},
+	}
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func programName() string {
+	return filepath.Base(os.Args[0])
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "使い方: %s <コマンド> [フラグ]\n\nコマンド:\n", programName())
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-16s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintf(out, "\n各コマンドのフラグは %s <コマンド> -h で表示します。\nコマンドを省略した場合は、以下のフラグを使用できます。\n\n", programName())
+	flag.PrintDefaults()
+}
+
+func newFlagSet(name string, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		cmd := findCommand(name)
+		fmt.Fprintf(fs.Output(), "使い方: %s %s [フラグ]%s\n\n%s\n\nフラグ:\n", programName(), name, args, cmd.description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func printVersion() {
+	fmt.Println("version:", version)
+	fmt.Println("revision:", revision)
+	fmt.Println("build:", build)
+}
+
+func exitWithError(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
+
+type setFlags []string
+
+func (s *setFlags) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *setFlags) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func configFlags(fs *flag.FlagSet) (*string, *setFlags) {
+	configPath := fs.String("c", defaultConfigPath, "設定ファイルのパス")
+	sets := &setFlags{}
+	fs.Var(sets, "set", "設定値の上書き (key.path=value の形式、複数指定可)")
+	return configPath, sets
+}
+
+// 設定ファイル、AIWOLF_* の環境変数、--set の順に適用する
+func loadConfig(path string, sets []string) (*model.Config, error) {
+	overrides, err := model.EnvOverrides(os.Environ())
+	if err != nil {
+		return nil, err
+	}
+	for _, set := range sets {
+		override, err := model.ParseOverride(set, "--set")
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, override)
+	}
+	config, err := model.LoadFromPath(path, overrides...)
+	if err != nil {
+		return nil, err
+	}
+	util.SetLogLanguage(model.LanguageFromString(config.Server.Language))
+	return config, nil
+}
+
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "")
+	configPath, sets := configFlags(fs)
+	fs.Parse(args)
+
+	config, err := loadConfig(*configPath, *sets)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("# %s\n%s", *configPath, config.Dump())
+	server, err := core.NewServer(*config)
+	if err != nil {
+		return err
+	}
+	server.SetConfigLoader(func() (*model.Config, error) {
+		return loadConfig(*configPath, *sets)
+	})
+	server.Run()
+	return nil
+}
+
+func runAnalyze(args []string) error {
+	fs := newFlagSet("analyze", "")
+	configPath, sets := configFlags(fs)
+	outputDir := fs.String("o", "", "レポートの出力ディレクトリ (省略時は analyzer.output_dir)")
+	fs.Parse(args)
+
+	config, err := loadConfig(*configPath, *sets)
+	if err != nil {
+		return err
+	}
+	if *outputDir != "" {
+		config.Analyzer.OutputDir = *outputDir
+	}
+	core.Analyzer(*config)
+	return nil
+}
+
+func runReduce(args []string) error {
+	fs := newFlagSet("reduce", "")
+	srcConfigPath := fs.String("s", "", "ソース設定ファイルのパス")
+	dstConfigPath := fs.String("d", "", "デスティネーション設定ファイルのパス")
+	fs.Parse(args)
+
+	if *srcConfigPath == "" || *dstConfigPath == "" {
+		fs.Usage()
+		return errors.New("-s と -d を指定してください")
+	}
+	srcConfig, err := loadConfig(*srcConfigPath, nil)
+	if err != nil {
+		return err
+	}
+	dstConfig, err := loadConfig(*dstConfigPath, nil)
+	if err != nil {
+		return err
+	}
+	core.Reduction(*srcConfig, *dstConfig)
+	return nil
+}
+
+func runValidateConfig(args []string) error {
+	fs := newFlagSet("validate-config", "")
+	configPath, sets := configFlags(fs)
+	printConfig := fs.Bool("print", false, "上書きを反映した設定を表示")
+	fs.Parse(args)
+
+	config, err := loadConfig(*configPath, *sets)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, *configPath+":")
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintln(os.Stderr, "  "+strings.TrimSpace(line))
+		}
+		return errors.New("設定ファイルに問題があります")
+	}
+	if *printConfig {
+		fmt.Print(config.Dump())
+	}
+	fmt.Println(*configPath + ": OK")
+	return nil
+}
+
+func runSchedule(args []string) error {
+	fs := newFlagSet("schedule", "")
+	configPath, sets := configFlags(fs)
+	teamCount := fs.Int("t", 0, "参加するチーム数 (省略時は match_optimizer.team_count)")
+	gameCount := fs.Int("g", 0, "全体のゲーム数 (省略時は match_optimizer.game_count)")
+	outputPath := fs.String("o", "", "スケジュールの出力ファイル (省略時は標準出力)")
+	fs.Parse(args)
+
+	config, err := loadConfig(*configPath, *sets)
+	if err != nil {
+		return err
+	}
+	if *teamCount > 0 {
+		config.MatchOptimizer.TeamCount = *teamCount
+	}
+	if *gameCount > 0 {
+		config.MatchOptimizer.GameCount = *gameCount
+	}
+	config.MatchOptimizer.OutputPath = *outputPath
+	mo, err := core.NewMatchOptimizerFromConfig(*config)
+	if err != nil {
+		return err
+	}
+	if *outputPath != "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(mo, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func runReplay(args []string) error {
+	fs := newFlagSet("replay", " <分析サービスのログ>")
+	delay := fs.Duration("delay", 0, "イベントごとの待機時間 (例: 500ms)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("ログファイルを1つ指定してください")
+	}
+	return core.Replay(fs.Arg(0), os.Stdout, *delay)
+}
+
+func runConvertLogs(args []string) error {
+	fs := newFlagSet("convert-logs", "")
+	configPath, sets := configFlags(fs)
+	inputDir := fs.String("i", "", "分析サービスのログのディレクトリ (省略時は analysis_service.output_dir)")
+	outputDir := fs.String("o", "", "従来形式のログの出力ディレクトリ (省略時は deprecated_log_service.output_dir)")
+	fs.Parse(args)
+
+	config, err := loadConfig(*configPath, *sets)
+	if err != nil {
+		return err
+	}
+	if *inputDir == "" {
+		*inputDir = config.AnalysisService.OutputDir
+	}
+	if *outputDir != "" {
+		config.DeprecatedLogService.OutputDir = *outputDir
+	}
+	count, err := core.ConvertLogs(*config, *inputDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d件のログを %s に変換しました\n", count, config.DeprecatedLogService.OutputDir)
+	return nil
+}
@@ -0,0 +1,300 @@
+package core
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kano-lab/aiwolf-nlp-server/logic"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/util"
+)
+
+func (s *Server) registerAdminRoutes(router *gin.Engine, token string) {
+	if token == "" {
+		slog.Warn("管理APIのトークンが設定されていないため、管理APIを無効にします")
+		return
+	}
+	admin := router.Group("/admin", s.authenticateAdmin)
+	admin.GET("/waiting_room", s.handleAdminWaitingRoom)
+	admin.DELETE("/waiting_room/:team/:name", s.handleAdminKick)
+	admin.GET("/games", s.handleAdminGames)
+	admin.POST("/games", s.handleAdminForceStart)
+	admin.POST("/games/:id/abort", s.handleAdminGameControl)
+	admin.POST("/games/:id/pause", s.handleAdminGameControl)
+	admin.POST("/games/:id/resume", s.handleAdminGameControl)
+	admin.POST("/games/:id/step", s.handleAdminGameStep)
+	admin.PUT("/games/:id/step_mode", s.handleAdminGameStepMode)
+	admin.GET("/games/:id/status", s.handleAdminGameStatus)
+	admin.GET("/matches", s.handleAdminMatches)
+	admin.POST("/matches", s.handleAdminAddMatch)
+	admin.PUT("/matches/:idx", s.handleAdminUpdateMatch)
+	admin.DELETE("/matches/:idx", s.handleAdminRemoveMatch)
+	admin.POST("/config/reload", s.handleAdminReloadConfig)
+	admin.GET("/drain", s.handleAdminDrainStatus)
+	admin.POST("/drain", s.handleAdminDrain)
+}
+
+func (s *Server) authenticateAdmin(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.currentConfig().AdminService.Token)) != 1 {
+		slog.Warn("管理APIの認証に失敗しました", "remote_addr", c.ClientIP())
+		s.adminError(c, 401, "unauthorized")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func (s *Server) adminError(c *gin.Context, code int, message string) {
+	c.JSON(code, gin.H{"error": util.TranslateForAcceptLanguage(message, c.GetHeader("Accept-Language"))})
+}
+
+func (s *Server) handleAdminWaitingRoom(c *gin.Context) {
+	c.JSON(200, gin.H{"teams": s.waitingRoom.GetConnectionList()})
+}
+
+func (s *Server) handleAdminKick(c *gin.Context) {
+	if err := s.waitingRoom.RemoveConnection(c.Param("team"), c.Param("name")); err != nil {
+		s.adminError(c, 404, err.Error())
+		return
+	}
+	c.JSON(200, gin.H{"team": c.Param("team"), "name": c.Param("name")})
+}
+
+func (s *Server) handleAdminGames(c *gin.Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	games := make([]gin.H, 0, len(s.games))
+	for _, game := range s.games {
+		agents := make([]gin.H, 0, len(game.Agents))
+		for _, agent := range game.Agents {
+			agents = append(agents, gin.H{
+				"agent": agent.String(),
+				"team":  agent.Team,
+				"name":  agent.Name,
+				"role":  agent.Role,
+			})
+		}
+		snapshot := game.Snapshot()
+		games = append(games, gin.H{
+			"game_id":     game.ID,
+			"day":         snapshot["day"],
+			"is_finished": snapshot["is_finished"],
+			"is_aborted":  snapshot["is_aborted"],
+			"is_paused":   snapshot["is_paused"],
+			"phase":       snapshot["phase"],
+			"agents":      agents,
+		})
+	}
+	c.JSON(200, gin.H{"games": games})
+}
+
+// マッチオプティマイザのスケジュールと整合しなくなるため、マッチオプティマイザが有効な場合は強制的に開始しない
+func (s *Server) handleAdminForceStart(c *gin.Context) {
+	if s.drain.active.Load() {
+		s.adminError(c, 503, "server is draining")
+		return
+	}
+	if s.matchOptimizer != nil {
+		s.adminError(c, 409, "force start is not available with match optimizer")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	connections, err := s.waitingRoom.GetAnyConnections()
+	if err != nil {
+		s.adminError(c, 409, err.Error())
+		return
+	}
+	config := s.config
+	game := logic.NewGame(&config, s.gameSettings, connections)
+	s.startGame(game)
+	slog.Info("管理APIからゲームを強制的に開始しました", "id", game.ID)
+	c.JSON(200, gin.H{"game_id": game.ID})
+}
+
+func (s *Server) handleAdminGameControl(c *gin.Context) {
+	game := s.findGame(c.Param("id"))
+	if game == nil {
+		s.adminError(c, 404, "game not found")
+		return
+	}
+	if game.Finished() {
+		s.adminError(c, 409, "game is finished")
+		return
+	}
+	switch c.FullPath() {
+	case "/admin/games/:id/abort":
+		game.Abort()
+	case "/admin/games/:id/pause":
+		game.Pause()
+	case "/admin/games/:id/resume":
+		game.Resume()
+	}
+	snapshot := game.Snapshot()
+	c.JSON(200, gin.H{"game_id": game.ID, "is_aborted": snapshot["is_aborted"], "is_paused": snapshot["is_paused"]})
+}
+
+func (s *Server) handleAdminGameStep(c *gin.Context) {
+	game := s.findGame(c.Param("id"))
+	if game == nil {
+		s.adminError(c, 404, "game not found")
+		return
+	}
+	if game.Finished() {
+		s.adminError(c, 409, "game is finished")
+		return
+	}
+	var body struct {
+		Count int `json:"count"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			s.adminError(c, 400, "invalid request body")
+			return
+		}
+	}
+	if body.Count <= 0 {
+		body.Count = 1
+	}
+	game.Step(body.Count)
+	c.JSON(200, game.Snapshot())
+}
+
+func (s *Server) handleAdminGameStepMode(c *gin.Context) {
+	game := s.findGame(c.Param("id"))
+	if game == nil {
+		s.adminError(c, 404, "game not found")
+		return
+	}
+	var body struct {
+		Enable *bool `json:"enable"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Enable == nil {
+		s.adminError(c, 400, "invalid request body")
+		return
+	}
+	game.SetStepMode(*body.Enable)
+	c.JSON(200, game.Snapshot())
+}
+
+func (s *Server) handleAdminGameStatus(c *gin.Context) {
+	game := s.findGame(c.Param("id"))
+	if game == nil {
+		s.adminError(c, 404, "game not found")
+		return
+	}
+	c.JSON(200, game.Snapshot())
+}
+
+func (s *Server) findGame(id string) *logic.Game {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, game := range s.games {
+		if game.ID == id {
+			return game
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleAdminMatches(c *gin.Context) {
+	if s.matchOptimizer == nil {
+		s.adminError(c, 404, "match optimizer is disabled")
+		return
+	}
+	c.JSON(200, gin.H{"scheduled_matches": s.matchOptimizer.getScheduledMatches()})
+}
+
+func (s *Server) handleAdminAddMatch(c *gin.Context) {
+	if s.matchOptimizer == nil {
+		s.adminError(c, 404, "match optimizer is disabled")
+		return
+	}
+	var body struct {
+		RoleTeams map[string][]string `json:"role_teams"`
+		Weight    *float64            `json:"weight"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		s.adminError(c, 400, "invalid request body")
+		return
+	}
+	match := make(map[model.Role][]string)
+	for role, teams := range body.RoleTeams {
+		match[model.RoleFromString(role)] = teams
+	}
+	weight := 1.0
+	if body.Weight != nil {
+		weight = *body.Weight
+	}
+	if err := s.matchOptimizer.addScheduledMatch(match, weight); err != nil {
+		s.adminError(c, 400, err.Error())
+		return
+	}
+	c.JSON(200, gin.H{"scheduled_matches": s.matchOptimizer.getScheduledMatches()})
+}
+
+func (s *Server) handleAdminUpdateMatch(c *gin.Context) {
+	if s.matchOptimizer == nil {
+		s.adminError(c, 404, "match optimizer is disabled")
+		return
+	}
+	idx, err := strconv.Atoi(c.Param("idx"))
+	if err != nil {
+		s.adminError(c, 400, "invalid match index")
+		return
+	}
+	var body struct {
+		Weight *float64 `json:"weight"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Weight == nil {
+		s.adminError(c, 400, "invalid request body")
+		return
+	}
+	if err := s.matchOptimizer.updateScheduledMatchWeight(idx, *body.Weight); err != nil {
+		s.adminError(c, 404, err.Error())
+		return
+	}
+	c.JSON(200, gin.H{"scheduled_matches": s.matchOptimizer.getScheduledMatches()})
+}
+
+func (s *Server) handleAdminRemoveMatch(c *gin.Context) {
+	if s.matchOptimizer == nil {
+		s.adminError(c, 404, "match optimizer is disabled")
+		return
+	}
+	idx, err := strconv.Atoi(c.Param("idx"))
+	if err != nil {
+		s.adminError(c, 400, "invalid match index")
+		return
+	}
+	if err := s.matchOptimizer.removeScheduledMatch(idx); err != nil {
+		s.adminError(c, 404, err.Error())
+		return
+	}
+	c.JSON(200, gin.H{"scheduled_matches": s.matchOptimizer.getScheduledMatches()})
+}
+
+func (s *Server) handleAdminReloadConfig(c *gin.Context) {
+	if err := s.Reload(); err != nil {
+		c.JSON(422, gin.H{
+			"error":   util.TranslateForAcceptLanguage("failed to reload config", c.GetHeader("Accept-Language")),
+			"details": strings.Split(err.Error(), "\n"),
+		})
+		return
+	}
+	c.JSON(200, gin.H{"reloaded": true})
+}
+
+func (s *Server) handleAdminDrainStatus(c *gin.Context) {
+	c.JSON(200, s.drainStatus())
+}
+
+func (s *Server) handleAdminDrain(c *gin.Context) {
+	slog.Info("管理APIからドレインが要求されました")
+	s.Drain()
+	c.JSON(202, s.drainStatus())
+}
@@ -2,160 +2,374 @@ package core
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"html/template"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/web"
 )
 
+type Report struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	Teams       []*TeamReport `json:"teams"`
+}
+
+type TeamReport struct {
+	Team  string                 `json:"team"`
+	Roles map[string]*RoleReport `json:"roles"`
+	Total RoleReport             `json:"total"`
+}
+
+type RoleReport struct {
+	Scheduled int `json:"scheduled"`
+	Ended     int `json:"ended"`
+	Count
+	ErrorRate float64 `json:"error_rate"`
+	Timing    Timing  `json:"timing"`
+}
+
+type Timing struct {
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+	MeanMs    float64 `json:"mean_ms"`
+	P50Ms     int64   `json:"p50_ms"`
+	P95Ms     int64   `json:"p95_ms"`
+	MaxMs     int64   `json:"max_ms"`
+	latencies []int64
+}
+
 func Analyzer(config model.Config) {
+	report := &Report{GeneratedAt: time.Now()}
+
 	data, err := os.ReadFile(config.MatchOptimizer.OutputPath)
 	if err != nil {
 		slog.Warn("マッチオプティマイザの読み込みに失敗しました", "error", err)
-		return
+	} else {
+		var mo MatchOptimizer
+		if err := json.Unmarshal(data, &mo); err != nil {
+			slog.Error("マッチオプティマイザのパースに失敗しました", "error", err)
+			return
+		}
+		slog.Info("マッチオプティマイザの統計データを分析します")
+		report.analyzeMatchOptimizer(&mo)
 	}
-	var mo MatchOptimizer
-	if err := json.Unmarshal(data, &mo); err != nil {
-		slog.Error("マッチオプティマイザのパースに失敗しました", "error", err)
+
+	if config.DeprecatedLogService.Enable {
+		slog.Info("ログサービスの統計データを分析します")
+		filePaths, err := filepath.Glob(filepath.Join(config.DeprecatedLogService.OutputDir, "*.log"))
+		if err != nil {
+			slog.Warn("ファイルの取得に失敗しました", "error", err)
+		}
+		for _, filePath := range filePaths {
+			report.analyzeDeprecatedLog(filePath)
+		}
+	}
+
+	if config.AnalysisService.Enable {
+		slog.Info("分析サービスの統計データを分析します")
+		filePaths, err := filepath.Glob(filepath.Join(config.AnalysisService.OutputDir, "*.json"))
+		if err != nil {
+			slog.Warn("ファイルの取得に失敗しました", "error", err)
+		}
+		for _, filePath := range filePaths {
+			report.analyzeAnalysisLog(filePath)
+		}
+	}
+
+	report.finalize()
+	if err := report.write(config.Analyzer.OutputDir, config.Analyzer.Html); err != nil {
+		slog.Error("レポートの出力に失敗しました", "error", err)
 		return
 	}
+	slog.Info("レポートを出力しました", "dir", config.Analyzer.OutputDir, "teams", len(report.Teams))
+}
 
-	slog.Info("マッチオプティマイザの統計データを分析します")
-	for idx, team := range mo.IdxTeamMap {
-		slog.Info("登録済みチームを取得しました", "idx", idx, "team", team)
+func (r *Report) team(team string) *TeamReport {
+	for _, t := range r.Teams {
+		if t.Team == team {
+			return t
+		}
+	}
+	t := &TeamReport{Team: team, Roles: make(map[string]*RoleReport)}
+	r.Teams = append(r.Teams, t)
+	return t
+}
 
-		scheduledRoles := make(map[model.Role]int)
+func (t *TeamReport) role(role model.Role) *RoleReport {
+	if _, exists := t.Roles[role.Name]; !exists {
+		t.Roles[role.Name] = &RoleReport{}
+	}
+	return t.Roles[role.Name]
+}
+
+func (r *Report) analyzeMatchOptimizer(mo *MatchOptimizer) {
+	for idx, team := range mo.IdxTeamMap {
+		t := r.team(team)
 		for _, match := range mo.ScheduledMatches {
 			for role, idxs := range match.RoleIdxs {
 				for _, i := range idxs {
 					if idx == i {
-						scheduledRoles[role]++
+						t.role(role).Scheduled++
 					}
 				}
 			}
 		}
-		sum := 0
-		for _, count := range scheduledRoles {
-			sum += count
-		}
-		slog.Info("スケジュールされた役職を取得しました", "idx", idx, "roles", scheduledRoles, "sum", sum)
-
-		endedRoles := make(map[model.Role]int)
 		for _, match := range mo.EndedMatches {
 			for role, idxs := range match {
 				for _, i := range idxs {
 					if idx == i {
-						endedRoles[role]++
+						t.role(role).Ended++
 					}
 				}
 			}
 		}
-		sum = 0
-		for _, count := range endedRoles {
-			sum += count
+	}
+}
+
+func (r *Report) analyzeDeprecatedLog(filePath string) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		slog.Warn("ファイルの読み込みに失敗しました", "error", err)
+		return
+	}
+	defer file.Close()
+
+	teamsRole := make(map[string]model.Role)
+	errorTeams := []string{}
+	var winSide *model.Team
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		values := strings.Split(line, ",")
+		if len(values) == 6 && values[1] == "status" {
+			if values[0] == "0" {
+				team := strings.TrimRight(values[5], "1234567890")
+				role := model.RoleFromString(values[3])
+				teamsRole[team] = role
+			} else {
+				team := strings.TrimRight(values[5], "1234567890")
+				status := values[4]
+				if status == "" {
+					errorTeams = append(errorTeams, team)
+				}
+			}
+		}
+		if len(values) == 5 && values[1] == "result" {
+			side := model.TeamFromString(values[4])
+			winSide = &side
 		}
-		slog.Info("終了した役職を取得しました", "idx", idx, "roles", endedRoles, "sum", sum)
 	}
 
-	if config.DeprecatedLogService.Enable {
-		slog.Info("ログサービスの統計データを分析します")
+	if len(teamsRole) == 0 {
+		slog.Warn("役職が取得できませんでした", "file", filePath)
+		return
+	}
 
-		filePaths, err := filepath.Glob(filepath.Join(config.DeprecatedLogService.OutputDir, "*.log"))
-		if err != nil {
-			slog.Warn("ファイルの取得に失敗しました", "error", err)
+	if winSide == nil {
+		slog.Warn("結果が取得できませんでした", "file", filePath)
+		return
+	}
+
+	for team, role := range teamsRole {
+		count := &r.team(team).role(role).Count
+		if slices.Contains(errorTeams, team) {
+			count.Error++
 		}
 
-		counts := make(map[string]map[model.Role]*Count)
+		if *winSide == model.T_NONE {
+			count.None++
+		} else {
+			count.Succeed++
 
-		for _, filePath := range filePaths {
-			file, err := os.Open(filePath)
-			if err != nil {
-				slog.Warn("ファイルの読み込みに失敗しました", "error", err)
-			}
-			defer file.Close()
-
-			teamsRole := make(map[string]model.Role)
-			errorTeams := []string{}
-			var winSide *model.Team
-
-			scanner := bufio.NewScanner(file)
-			for scanner.Scan() {
-				line := scanner.Text()
-				values := strings.Split(line, ",")
-				if len(values) == 6 && values[1] == "status" {
-					if values[0] == "0" {
-						team := strings.TrimRight(values[5], "1234567890")
-						role := model.RoleFromString(values[3])
-						teamsRole[team] = role
-					} else {
-						team := strings.TrimRight(values[5], "1234567890")
-						status := values[4]
-						if status == "" {
-							errorTeams = append(errorTeams, team)
-						}
-					}
-				}
-				if len(values) == 5 && values[1] == "result" {
-					side := model.TeamFromString(values[4])
-					winSide = &side
-				}
+			if *winSide == model.T_VILLAGER && role != model.R_WEREWOLF && role != model.R_POSSESSED {
+				count.Win++
+			} else if *winSide == model.T_WEREWOLF && (role == model.R_WEREWOLF || role == model.R_POSSESSED) {
+				count.Win++
+			} else {
+				count.Lose++
 			}
+		}
+	}
+}
 
-			if len(teamsRole) == 0 {
-				slog.Warn("役職が取得できませんでした", "file", filePath)
-				continue
-			}
+func (r *Report) analyzeAnalysisLog(filePath string) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		slog.Warn("ファイルの読み込みに失敗しました", "error", err)
+		return
+	}
+	var game struct {
+		Agents []struct {
+			Agent string `json:"agent"`
+			Team  string `json:"team"`
+			Role  string `json:"role"`
+		} `json:"agents"`
+		Entries []struct {
+			Agent             string `json:"agent"`
+			RequestTimestamp  *int64 `json:"request_timestamp"`
+			ResponseTimestamp int64  `json:"response_timestamp"`
+			Error             string `json:"error"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &game); err != nil {
+		slog.Warn("ファイルのパースに失敗しました", "file", filePath, "error", err)
+		return
+	}
 
-			if winSide == nil {
-				slog.Warn("結果が取得できませんでした", "file", filePath)
-				continue
-			}
+	agents := make(map[string]*RoleReport)
+	for _, agent := range game.Agents {
+		agents[agent.Agent] = r.team(agent.Team).role(model.RoleFromString(agent.Role))
+	}
+	for _, entry := range game.Entries {
+		role, exists := agents[entry.Agent]
+		if !exists || entry.RequestTimestamp == nil {
+			continue
+		}
+		role.Timing.Requests++
+		if entry.Error != "" {
+			role.Timing.Errors++
+		}
+		role.Timing.latencies = append(role.Timing.latencies, entry.ResponseTimestamp-*entry.RequestTimestamp)
+	}
+}
 
-			for team, role := range teamsRole {
-				if _, exists := counts[team]; !exists {
-					counts[team] = make(map[model.Role]*Count)
-				}
-				if _, exists := counts[team][role]; !exists {
-					counts[team][role] = &Count{}
-				}
+func (r *Report) finalize() {
+	slices.SortFunc(r.Teams, func(a, b *TeamReport) int {
+		return strings.Compare(a.Team, b.Team)
+	})
+	for _, t := range r.Teams {
+		t.Total = RoleReport{}
+		for _, role := range t.Roles {
+			role.finalize()
+			t.Total.Scheduled += role.Scheduled
+			t.Total.Ended += role.Ended
+			t.Total.Succeed += role.Succeed
+			t.Total.None += role.None
+			t.Total.Win += role.Win
+			t.Total.Lose += role.Lose
+			t.Total.Error += role.Error
+			t.Total.Timing.Requests += role.Timing.Requests
+			t.Total.Timing.Errors += role.Timing.Errors
+			t.Total.Timing.latencies = append(t.Total.Timing.latencies, role.Timing.latencies...)
+		}
+		t.Total.finalize()
+	}
+}
 
-				if slices.Contains(errorTeams, team) {
-					counts[team][role].Error++
-				}
+func (r *RoleReport) finalize() {
+	if games := r.Succeed + r.None; games > 0 {
+		r.ErrorRate = float64(r.Error) / float64(games)
+	}
+	latencies := slices.Sorted(slices.Values(r.Timing.latencies))
+	if len(latencies) == 0 {
+		return
+	}
+	var sum int64
+	for _, latency := range latencies {
+		sum += latency
+	}
+	r.Timing.MeanMs = float64(sum) / float64(len(latencies))
+	r.Timing.P50Ms = latencies[(len(latencies)-1)*50/100]
+	r.Timing.P95Ms = latencies[(len(latencies)-1)*95/100]
+	r.Timing.MaxMs = latencies[len(latencies)-1]
+}
 
-				if *winSide == model.T_NONE {
-					counts[team][role].None++
-				} else {
-					counts[team][role].Succeed++
-
-					if *winSide == model.T_VILLAGER && role != model.R_WEREWOLF && role != model.R_POSSESSED {
-						counts[team][role].Win++
-					} else if *winSide == model.T_WEREWOLF && (role == model.R_WEREWOLF || role == model.R_POSSESSED) {
-						counts[team][role].Win++
-					} else {
-						counts[team][role].Lose++
-					}
-				}
-			}
+func (r *Report) write(dir string, html bool) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	jsonData, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "report.json"), jsonData, 0644); err != nil {
+		return err
+	}
+
+	type row struct {
+		Role string
+		*RoleReport
+	}
+	type section struct {
+		Team string
+		Rows []row
+	}
+	sections := make([]section, 0, len(r.Teams))
+	for _, t := range r.Teams {
+		rows := make([]row, 0, len(t.Roles)+1)
+		for _, role := range slices.Sorted(maps.Keys(t.Roles)) {
+			rows = append(rows, row{Role: role, RoleReport: t.Roles[role]})
 		}
+		rows = append(rows, row{Role: "ALL", RoleReport: &t.Total})
+		sections = append(sections, section{Team: t.Team, Rows: rows})
+	}
 
-		for team, roles := range counts {
-			global := &Count{}
-			for role, count := range roles {
-				slog.Info("統計データを取得しました", "team", team, "role", role, "win", count.Win, "lose", count.Lose, "error", count.Error, "none", count.None, "succeed", count.Succeed)
-				global.Win += count.Win
-				global.Lose += count.Lose
-				global.Error += count.Error
-				global.None += count.None
-				global.Succeed += count.Succeed
-			}
-			slog.Info("統計データを取得しました", "team", team, "win", global.Win, "lose", global.Lose, "error", global.Error, "none", global.None, "succeed", global.Succeed)
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"team", "role", "scheduled", "ended", "succeed", "none", "win", "lose", "error", "error_rate", "requests", "request_errors", "mean_ms", "p50_ms", "p95_ms", "max_ms"})
+	for _, section := range sections {
+		for _, row := range section.Rows {
+			writer.Write(row.record(section.Team, row.Role))
+		}
+	}
+	writer.Flush()
+	if err := os.WriteFile(filepath.Join(dir, "report.csv"), buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	if html {
+		tmpl, err := template.New("report").Funcs(template.FuncMap{
+			"percent": func(rate float64) string {
+				return strconv.FormatFloat(rate*100, 'f', 1, 64) + "%"
+			},
+		}).Parse(web.Report)
+		if err != nil {
+			return err
+		}
+		buf.Reset()
+		if err := tmpl.Execute(&buf, map[string]interface{}{
+			"GeneratedAt": r.GeneratedAt,
+			"Teams":       sections,
+		}); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "report.html"), buf.Bytes(), 0644); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+func (r RoleReport) record(team string, role string) []string {
+	return []string{
+		team,
+		role,
+		strconv.Itoa(r.Scheduled),
+		strconv.Itoa(r.Ended),
+		strconv.Itoa(r.Succeed),
+		strconv.Itoa(r.None),
+		strconv.Itoa(r.Win),
+		strconv.Itoa(r.Lose),
+		strconv.Itoa(r.Error),
+		strconv.FormatFloat(r.ErrorRate, 'f', 4, 64),
+		strconv.Itoa(r.Timing.Requests),
+		strconv.Itoa(r.Timing.Errors),
+		strconv.FormatFloat(r.Timing.MeanMs, 'f', 1, 64),
+		strconv.FormatInt(r.Timing.P50Ms, 10),
+		strconv.FormatInt(r.Timing.P95Ms, 10),
+		strconv.FormatInt(r.Timing.MaxMs, 10),
+	}
 }
 
 func Reduction(src model.Config, dst model.Config) {
@@ -197,9 +411,9 @@ func Reduction(src model.Config, dst model.Config) {
 }
 
 type Count struct {
-	Succeed int
-	None    int
-	Win     int
-	Lose    int
-	Error   int
+	Succeed int `json:"succeed"`
+	None    int `json:"none"`
+	Win     int `json:"win"`
+	Lose    int `json:"lose"`
+	Error   int `json:"error"`
 }
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"log/slog"
+
+	"github.com/kano-lab/aiwolf-nlp-server/logic"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+type pendingGame struct {
+	checkpoint  *logic.Checkpoint
+	connections map[int]model.Connection
+}
+
+func (s *Server) loadCheckpoints() error {
+	checkpoints, err := logic.LoadCheckpoints(s.config.Checkpoint.OutputDir)
+	if err != nil {
+		return err
+	}
+	for _, checkpoint := range checkpoints {
+		s.pendingGames = append(s.pendingGames, &pendingGame{
+			checkpoint:  checkpoint,
+			connections: make(map[int]model.Connection),
+		})
+	}
+	return nil
+}
+
+// 再開待ちのゲームのエージェントであれば接続を割り当て、全員が揃った時点でゲームを再開する
+func (s *Server) resumeGame(connection model.Connection) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, pending := range s.pendingGames {
+		for _, agent := range pending.checkpoint.Agents {
+			if _, exists := pending.connections[agent.Idx]; exists || agent.Team != connection.Team || agent.Name != connection.Name {
+				continue
+			}
+			pending.connections[agent.Idx] = connection
+			slog.Info("再開待ちのゲームにエージェントが再接続しました", "id", pending.checkpoint.GameID, "team", connection.Team, "name", connection.Name)
+			if len(pending.connections) < len(pending.checkpoint.Agents) {
+				return true
+			}
+			s.pendingGames = append(s.pendingGames[:i], s.pendingGames[i+1:]...)
+			config := s.config
+			game, err := logic.NewGameFromCheckpoint(&config, s.gameSettings, pending.checkpoint, pending.connections)
+			if err != nil {
+				slog.Error("チェックポイントからのゲームの再開に失敗しました", "id", pending.checkpoint.GameID, "error", err)
+				s.abortPendingGame(pending, err.Error())
+				go s.returnToWaitingRoom(pending.connections)
+				return true
+			}
+			s.startGame(game)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) abortPendingGames(reason string) {
+	s.mu.Lock()
+	pendingGames := s.pendingGames
+	s.pendingGames = nil
+	for _, pending := range pendingGames {
+		s.abortPendingGame(pending, reason)
+	}
+	s.mu.Unlock()
+	for _, pending := range pendingGames {
+		s.returnToWaitingRoom(pending.connections)
+	}
+}
+
+func (s *Server) abortPendingGame(pending *pendingGame, reason string) {
+	if err := pending.checkpoint.Abort(reason); err != nil {
+		slog.Error("チェックポイントの保存に失敗しました", "id", pending.checkpoint.GameID, "error", err)
+	}
+	if s.config.MatchOptimizer.Enable {
+		s.matchOptimizer.rescheduleMatch(pending.checkpoint.RoleTeamNames(), reason+" ("+pending.checkpoint.GameID+")")
+	}
+}
+
+func (s *Server) returnToWaitingRoom(connections map[int]model.Connection) {
+	for _, connection := range connections {
+		s.waitingRoom.AddConnection(connection.Team, connection)
+		s.matchGame()
+	}
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/kano-lab/aiwolf-nlp-server/logic"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+type drainState struct {
+	active    atomic.Bool
+	once      sync.Once
+	done      chan struct{}
+	startedAt time.Time
+	deadline  time.Time
+}
+
+// マッチングを停止し、進行中のゲームの終了を待ってからHTTPサーバを停止する
+func (s *Server) Drain() {
+	s.drain.once.Do(func() {
+		shutdown := s.currentConfig().Server.Shutdown
+		s.drain.startedAt = time.Now()
+		s.drain.deadline = s.drain.startedAt.Add(shutdown.Timeout)
+		s.drain.active.Store(true)
+		slog.Info("ドレインを開始しました", "deadline", s.drain.deadline)
+		go s.runDrain(shutdown.ProgressInterval, shutdown.Timeout)
+	})
+}
+
+func (s *Server) runDrain(progressInterval time.Duration, timeout time.Duration) {
+	s.closeWaitingConnections()
+
+	progress := time.NewTicker(progressInterval)
+	defer progress.Stop()
+	deadline := time.After(timeout)
+	for {
+		running := s.runningGames()
+		if len(running) == 0 {
+			break
+		}
+		select {
+		case <-progress.C:
+			for _, game := range running {
+				snapshot := game.Snapshot()
+				slog.Info("進行中のゲームの終了を待機しています", "id", game.ID, "day", snapshot["day"], "phase", snapshot["phase"], "is_paused", snapshot["is_paused"])
+			}
+		case <-deadline:
+			slog.Warn("ドレインの期限を過ぎたため、進行中のゲームを中断します", "count", len(running))
+			for _, game := range running {
+				game.Abort()
+			}
+		case <-time.After(time.Second):
+		}
+	}
+	slog.Info("全てのゲームが終了しました")
+
+	if s.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			slog.Error("HTTPサーバの停止に失敗しました", "error", err)
+		}
+	}
+	slog.Info("サーバを停止しました")
+	close(s.drain.done)
+}
+
+// 再開待ちのゲームのチェックポイントは次回の起動時に再開できるように残す
+func (s *Server) closeWaitingConnections() {
+	message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+	closeConnection := func(connection model.Connection) {
+		connection.Conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+		connection.Conn.Close()
+	}
+	for _, connection := range s.waitingRoom.RemoveAllConnections() {
+		closeConnection(connection)
+	}
+	s.mu.Lock()
+	pendingGames := s.pendingGames
+	s.pendingGames = nil
+	s.mu.Unlock()
+	for _, pending := range pendingGames {
+		for _, connection := range pending.connections {
+			closeConnection(connection)
+		}
+	}
+	slog.Info("待機中のクライアントにサーバの停止を通知しました")
+}
+
+func (s *Server) runningGames() []*logic.Game {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	running := make([]*logic.Game, 0)
+	for _, game := range s.games {
+		if !game.Finished() {
+			running = append(running, game)
+		}
+	}
+	return running
+}
+
+func (s *Server) drainStatus() gin.H {
+	if !s.drain.active.Load() {
+		return gin.H{"draining": false}
+	}
+	games := make([]gin.H, 0)
+	for _, game := range s.runningGames() {
+		snapshot := game.Snapshot()
+		games = append(games, gin.H{
+			"game_id":    game.ID,
+			"day":        snapshot["day"],
+			"phase":      snapshot["phase"],
+			"is_paused":  snapshot["is_paused"],
+			"is_aborted": snapshot["is_aborted"],
+		})
+	}
+	return gin.H{
+		"draining":   true,
+		"started_at": s.drain.startedAt,
+		"deadline":   s.drain.deadline,
+		"games":      games,
+	}
+}
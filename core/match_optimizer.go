@@ -63,6 +63,7 @@ func (mo *MatchOptimizer) UnmarshalJSON(data []byte) error {
 		ScheduledMatches []struct {
 			RoleIdxs map[string][]int `json:"role_idxs"`
 			Weight   float64          `json:"weight"`
+			Reason   string           `json:"reason"`
 		} `json:"scheduled_matches"`
 	}{
 		Alias: (*Alias)(mo),
@@ -86,6 +87,7 @@ func (mo *MatchOptimizer) UnmarshalJSON(data []byte) error {
 		mo.ScheduledMatches[i] = model.MatchWeight{
 			RoleIdxs: make(map[model.Role][]int),
 			Weight:   scheduledMatch.Weight,
+			Reason:   scheduledMatch.Reason,
 		}
 		for role, idxs := range scheduledMatch.RoleIdxs {
 			mo.ScheduledMatches[i].RoleIdxs[model.RoleFromString(role)] = idxs
@@ -250,7 +252,115 @@ func (mo *MatchOptimizer) setMatchWeight(match map[model.Role][]string, weight f
 	slog.Warn("スケジュールされたマッチが見つかりませんでした")
 }
 
+// 中断されたゲームのマッチを再び実行されるようにし、理由を記録する
+func (mo *MatchOptimizer) rescheduleMatch(match map[model.Role][]string, reason string) {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	idxMatch := util.TeamNameMatchToIdxMatch(mo.IdxTeamMap, match)
+
+	for i, scheduledMatch := range mo.ScheduledMatches {
+		if scheduledMatch.Equal(model.MatchWeight{RoleIdxs: idxMatch}) {
+			if mo.ScheduledMatches[i].Weight <= 0 {
+				mo.ScheduledMatches[i].Weight = 1.0
+			}
+			mo.ScheduledMatches[i].Reason = reason
+			slog.Info("マッチを再スケジュールしました", "weight", mo.ScheduledMatches[i].Weight, "reason", reason)
+			mo.save()
+			return
+		}
+	}
+	mo.ScheduledMatches = append(mo.ScheduledMatches, model.MatchWeight{
+		RoleIdxs: idxMatch,
+		Weight:   1.0,
+		Reason:   reason,
+	})
+	slog.Info("マッチを再スケジュールしました", "weight", 1.0, "reason", reason)
+	mo.save()
+}
+
+func (mo *MatchOptimizer) getScheduledMatches() []map[string]interface{} {
+	mo.mu.RLock()
+	defer mo.mu.RUnlock()
+	scheduledMatches := make([]map[string]interface{}, len(mo.ScheduledMatches))
+	for i, scheduledMatch := range mo.ScheduledMatches {
+		roleTeams := make(map[string][]string)
+		for role, teams := range util.IdxMatchToTeamNameMatch(mo.IdxTeamMap, scheduledMatch.RoleIdxs) {
+			roleTeams[role.String()] = teams
+		}
+		scheduledMatches[i] = map[string]interface{}{
+			"idx":        i,
+			"role_teams": roleTeams,
+			"weight":     scheduledMatch.Weight,
+			"reason":     scheduledMatch.Reason,
+		}
+	}
+	return scheduledMatches
+}
+
+func (mo *MatchOptimizer) addScheduledMatch(match map[model.Role][]string, weight float64) error {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	for role, num := range mo.RoleNumMap {
+		if len(match[role]) != num {
+			return errors.New("マッチの役職の人数が一致しません")
+		}
+	}
+	for role, teams := range match {
+		if mo.RoleNumMap[role] != len(teams) {
+			return errors.New("マッチの役職の人数が一致しません")
+		}
+	}
+	idxMatch := util.TeamNameMatchToIdxMatch(mo.IdxTeamMap, match)
+	for _, idxs := range idxMatch {
+		for _, idx := range idxs {
+			if idx == -1 {
+				return errors.New("マッチに登録されていないチームが含まれています")
+			}
+		}
+	}
+	mo.ScheduledMatches = append(mo.ScheduledMatches, model.MatchWeight{
+		RoleIdxs: idxMatch,
+		Weight:   weight,
+	})
+	slog.Info("スケジュールされたマッチを追加しました", "weight", weight)
+	mo.save()
+	return nil
+}
+
+func (mo *MatchOptimizer) updateScheduledMatchWeight(idx int, weight float64) error {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	if idx < 0 || idx >= len(mo.ScheduledMatches) {
+		return errors.New("スケジュールされたマッチが見つかりませんでした")
+	}
+	mo.ScheduledMatches[idx].Weight = weight
+	slog.Info("スケジュールされたマッチの重みを設定しました", "weight", weight)
+	mo.save()
+	return nil
+}
+
+func (mo *MatchOptimizer) removeScheduledMatch(idx int) error {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	if idx < 0 || idx >= len(mo.ScheduledMatches) {
+		return errors.New("スケジュールされたマッチが見つかりませんでした")
+	}
+	mo.ScheduledMatches = append(mo.ScheduledMatches[:idx], mo.ScheduledMatches[idx+1:]...)
+	slog.Info("スケジュールされたマッチから削除しました", "length", len(mo.ScheduledMatches))
+	mo.save()
+	return nil
+}
+
+func (mo *MatchOptimizer) setInfiniteLoop(infiniteLoop bool) {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	mo.InfiniteLoop = infiniteLoop
+}
+
 func (mo *MatchOptimizer) save() error {
+	if mo.outputPath == "" {
+		return nil
+	}
 	jsonData, err := json.Marshal(mo)
 	if err != nil {
 		return err
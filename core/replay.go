@@ -0,0 +1,249 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/service"
+)
+
+type GameLog struct {
+	GameID  string `json:"game_id"`
+	WinSide string `json:"win_side"`
+	Agents  []struct {
+		Agent string `json:"agent"`
+		Idx   int    `json:"idx"`
+		Team  string `json:"team"`
+		Name  string `json:"name"`
+		Role  string `json:"role"`
+	} `json:"agents"`
+	Timeline []struct {
+		Type      string  `json:"type"`
+		Day       int     `json:"day"`
+		Idx       int     `json:"idx"`
+		Turn      int     `json:"turn"`
+		Agent     *string `json:"agent"`
+		Target    string  `json:"target"`
+		Text      string  `json:"text"`
+		Result    string  `json:"result"`
+		Succeeded bool    `json:"succeeded"`
+		WinSide   string  `json:"win_side"`
+		Decision  string  `json:"decision"`
+	} `json:"timeline"`
+}
+
+func LoadGameLog(path string) (*GameLog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var gameLog GameLog
+	if err := json.Unmarshal(data, &gameLog); err != nil {
+		return nil, err
+	}
+	if gameLog.GameID == "" || len(gameLog.Agents) == 0 {
+		return nil, errors.New("ゲームログにゲームIDもしくはエージェントが含まれていません")
+	}
+	return &gameLog, nil
+}
+
+// 分析サービスのタイムラインからゲームのイベントを再構築する
+func (g *GameLog) Events() []model.Event {
+	agents := make([]model.Agent, 0, len(g.Agents))
+	agentMap := make(map[string]model.Agent)
+	statusMap := make(map[model.Agent]model.Status)
+	for _, a := range g.Agents {
+		agent := model.Agent{Idx: a.Idx, Team: a.Team, Name: a.Name, Role: model.RoleFromString(a.Role)}
+		agents = append(agents, agent)
+		agentMap[a.Agent] = agent
+		statusMap[agent] = model.S_ALIVE
+	}
+	copyStatusMap := func() map[model.Agent]model.Status {
+		copied := make(map[model.Agent]model.Status, len(statusMap))
+		for agent, status := range statusMap {
+			copied[agent] = status
+		}
+		return copied
+	}
+	agent := func(name *string) model.Agent {
+		if name == nil {
+			return model.Agent{}
+		}
+		return agentMap[*name]
+	}
+
+	events := []model.Event{model.GameStartedEvent{EventHeader: model.EventHeader{GameID: g.GameID}, Agents: agents}}
+	for _, entry := range g.Timeline {
+		header := model.EventHeader{GameID: g.GameID, Day: entry.Day}
+		target := agentMap[entry.Target]
+		switch entry.Type {
+		case "day_started":
+			events = append(events, model.DayStartedEvent{EventHeader: header, Agents: agents, StatusMap: copyStatusMap()})
+		case "talk", "whisper":
+			request := model.R_TALK
+			if entry.Type == "whisper" {
+				request = model.R_WHISPER
+			}
+			events = append(events, model.TalkSpokenEvent{EventHeader: header, Request: request, Talk: model.Talk{
+				Idx: entry.Idx, Day: entry.Day, Turn: entry.Turn, Agent: agent(entry.Agent), Text: entry.Text,
+			}, Decision: model.Decision(entry.Decision)})
+		case "vote", "declare_vote", "attack_vote":
+			request := model.R_VOTE
+			switch entry.Type {
+			case "declare_vote":
+				request = model.R_DECLARE_VOTE
+			case "attack_vote":
+				request = model.R_ATTACK
+			}
+			events = append(events, model.VoteCastEvent{EventHeader: header, Request: request, Vote: model.Vote{
+				Day: entry.Day, Agent: agent(entry.Agent), Target: target,
+			}, Decision: model.Decision(entry.Decision)})
+		case "execute":
+			statusMap[agent(entry.Agent)] = model.S_DEAD
+			events = append(events, model.ExecutedEvent{EventHeader: header, Agent: agent(entry.Agent)})
+		case "divine":
+			events = append(events, model.DivinedEvent{EventHeader: header, Judge: model.Judge{
+				Day: entry.Day, Agent: agent(entry.Agent), Target: target, Result: model.SpeciesFromString(entry.Result),
+			}, Decision: model.Decision(entry.Decision)})
+		case "guard":
+			events = append(events, model.GuardedEvent{EventHeader: header, Guard: model.Guard{
+				Day: entry.Day, Agent: agent(entry.Agent), Target: target,
+			}, Decision: model.Decision(entry.Decision)})
+		case "attack":
+			// 以前のログでは襲撃投票も attack として記録されている
+			if entry.Target != "" {
+				events = append(events, model.VoteCastEvent{EventHeader: header, Request: model.R_ATTACK, Vote: model.Vote{
+					Day: entry.Day, Agent: agent(entry.Agent), Target: target,
+				}})
+				continue
+			}
+			var attacked *model.Agent
+			if entry.Agent != nil {
+				a := agent(entry.Agent)
+				attacked = &a
+				if entry.Succeeded {
+					statusMap[a] = model.S_DEAD
+				}
+			}
+			events = append(events, model.AttackedEvent{EventHeader: header, Agent: attacked, Succeeded: entry.Succeeded})
+		case "game_ended":
+			villagers, werewolves := 0, 0
+			for agent, status := range statusMap {
+				if status != model.S_ALIVE {
+					continue
+				}
+				if agent.Role.Species == model.S_WEREWOLF {
+					werewolves++
+				} else {
+					villagers++
+				}
+			}
+			events = append(events, model.GameEndedEvent{
+				EventHeader: header,
+				WinSide:     model.TeamFromString(entry.WinSide),
+				Agents:      agents,
+				StatusMap:   copyStatusMap(),
+				Villagers:   villagers,
+				Werewolves:  werewolves,
+			})
+		}
+	}
+	return events
+}
+
+func Replay(path string, w io.Writer, delay time.Duration) error {
+	gameLog, err := LoadGameLog(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "ゲームID: %s\n", gameLog.GameID)
+	for _, a := range gameLog.Agents {
+		fmt.Fprintf(w, "%s: %s (%s)\n", a.Agent, a.Role, a.Team)
+	}
+	name := func(agent model.Agent) string {
+		if agent.Idx == 0 {
+			return "なし"
+		}
+		return agent.String()
+	}
+	substituted := func(decision model.Decision) string {
+		if decision == model.D_SERVER_SUBSTITUTED {
+			return " (サーバによる代替)"
+		}
+		return ""
+	}
+	for _, event := range gameLog.Events() {
+		var line string
+		switch e := event.(type) {
+		case model.DayStartedEvent:
+			line = fmt.Sprintf("=== %d日目 ===", e.Day)
+		case model.TalkSpokenEvent:
+			if e.Request == model.R_WHISPER {
+				line = fmt.Sprintf("[囁き] %s: %s%s", name(e.Talk.Agent), e.Talk.Text, substituted(e.Decision))
+			} else {
+				line = fmt.Sprintf("%s: %s%s", name(e.Talk.Agent), e.Talk.Text, substituted(e.Decision))
+			}
+		case model.VoteCastEvent:
+			label := map[model.Request]string{model.R_VOTE: "投票", model.R_DECLARE_VOTE: "投票宣言", model.R_ATTACK: "襲撃投票"}[e.Request]
+			line = fmt.Sprintf("[%s] %s → %s%s", label, name(e.Vote.Agent), name(e.Vote.Target), substituted(e.Decision))
+		case model.ExecutedEvent:
+			line = fmt.Sprintf("%s が追放されました", name(e.Agent))
+		case model.DivinedEvent:
+			line = fmt.Sprintf("[占い] %s → %s: %s%s", name(e.Judge.Agent), name(e.Judge.Target), e.Judge.Result, substituted(e.Decision))
+		case model.GuardedEvent:
+			line = fmt.Sprintf("[護衛] %s → %s%s", name(e.Guard.Agent), name(e.Guard.Target), substituted(e.Decision))
+		case model.AttackedEvent:
+			if e.Agent == nil {
+				line = "襲撃はありませんでした"
+			} else if e.Succeeded {
+				line = fmt.Sprintf("%s が襲撃されました", name(*e.Agent))
+			} else {
+				line = fmt.Sprintf("%s への襲撃は護衛されました", name(*e.Agent))
+			}
+		case model.GameEndedEvent:
+			line = fmt.Sprintf("ゲームが終了しました (勝利陣営: %s)", e.WinSide)
+		default:
+			continue
+		}
+		fmt.Fprintln(w, line)
+		time.Sleep(delay)
+	}
+	return nil
+}
+
+// 分析サービスのログから従来形式のログを出力する
+func ConvertLogs(config model.Config, srcDir string) (int, error) {
+	filePaths, err := filepath.Glob(filepath.Join(srcDir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	deprecatedLogService := service.NewDeprecatedLogService(config)
+	count := 0
+	for _, filePath := range filePaths {
+		if filepath.Base(filePath) == filepath.Base(config.MatchOptimizer.OutputPath) {
+			continue
+		}
+		gameLog, err := LoadGameLog(filePath)
+		if err != nil {
+			slog.Warn("ファイルのパースに失敗しました", "file", filePath, "error", err)
+			continue
+		}
+		events := gameLog.Events()
+		if _, ok := events[len(events)-1].(model.GameEndedEvent); !ok {
+			slog.Warn("結果が取得できませんでした", "file", filePath)
+			continue
+		}
+		for _, event := range events {
+			deprecatedLogService.HandleEvent(event)
+		}
+		count++
+	}
+	return count, nil
+}
@@ -1,10 +1,13 @@
 package core
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
+	"slices"
 	"strconv"
 	"sync"
 	"syscall"
@@ -16,6 +19,7 @@ import (
 	"github.com/kano-lab/aiwolf-nlp-server/model"
 	"github.com/kano-lab/aiwolf-nlp-server/service"
 	"github.com/kano-lab/aiwolf-nlp-server/util"
+	"github.com/kano-lab/aiwolf-nlp-server/web"
 )
 
 type Server struct {
@@ -26,13 +30,22 @@ type Server struct {
 	gameSettings         *model.Settings
 	games                []*logic.Game
 	mu                   sync.RWMutex
-	signaled             bool
+	httpServer           *http.Server
+	drain                drainState
 	analysisService      *service.AnalysisService
 	apiService           *service.ApiService
 	deprecatedLogService *service.DeprecatedLogService
+	metricsService       *service.MetricsService
+	eventBus             *service.EventBus
+	configLoader         func() (*model.Config, error)
+	pendingGames         []*pendingGame
 }
 
-func NewServer(config model.Config) *Server {
+func NewServer(config model.Config) (*Server, error) {
+	if err := config.Validate(); err != nil {
+		slog.Error("設定ファイルの検証に失敗しました", "error", err)
+		return nil, err
+	}
 	server := &Server{
 		config: config,
 		upgrader: websocket.Upgrader{
@@ -43,90 +56,196 @@ func NewServer(config model.Config) *Server {
 		waitingRoom: NewWaitingRoom(config),
 		games:       make([]*logic.Game, 0),
 		mu:          sync.RWMutex{},
-		signaled:    false,
+		drain:       drainState{done: make(chan struct{})},
+		eventBus:    service.NewEventBus(),
 	}
 	gameSettings, err := model.NewSettings(config)
 	if err != nil {
 		slog.Error("ゲーム設定の作成に失敗しました", "error", err)
-		return nil
+		return nil, err
 	}
 	server.gameSettings = gameSettings
 	if config.AnalysisService.Enable {
 		server.analysisService = service.NewAnalysisService(config)
+		server.eventBus.Subscribe(server.analysisService)
 	}
 	if config.ApiService.Enable {
-		if server.analysisService == nil {
-			slog.Error("APIサービスの作成に失敗しました", "error", "analysis service is nil")
-		} else {
-			server.apiService = service.NewApiService(server.analysisService, config)
-		}
+		server.apiService = service.NewApiService(server.analysisService, config)
 	}
 	if config.DeprecatedLogService.Enable {
 		server.deprecatedLogService = service.NewDeprecatedLogService(config)
+		server.eventBus.Subscribe(server.deprecatedLogService)
+	}
+	if config.MetricsService.Enable {
+		server.metricsService = service.NewMetricsService(config)
+		server.metricsService.SetWaitingRoomSizes(server.waitingRoom.CountByTeam)
+		server.eventBus.Subscribe(server.metricsService)
+	}
+	if config.Game.Debug.StepMode && !config.AdminService.Enable {
+		slog.Warn("管理APIが無効なため、ステップ実行モードのゲームを操作できません")
 	}
 	if config.MatchOptimizer.Enable {
 		matchOptimizer, err := NewMatchOptimizer(config)
 		if err != nil {
 			slog.Error("マッチオプティマイザの作成に失敗しました", "error", err)
-			return nil
+			return nil, err
 		}
 		server.matchOptimizer = matchOptimizer
 	}
-	return server
+	if config.Checkpoint.Enable {
+		if err := server.loadCheckpoints(); err != nil {
+			slog.Error("チェックポイントの読み込みに失敗しました", "error", err)
+			return nil, err
+		}
+	}
+	return server, nil
 }
 
 func (s *Server) Run() {
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.Default()
+	config := s.currentConfig()
 
 	router.GET("/ws", func(c *gin.Context) {
 		s.handleConnections(c.Writer, c.Request)
 	})
 
-	if s.config.ApiService.Enable {
+	if config.ApiService.Enable {
 		s.apiService.RegisterRoutes(router)
 	}
 
+	if config.MetricsService.Enable {
+		s.metricsService.RegisterRoutes(router)
+	}
+
+	if config.AdminService.Enable {
+		s.registerAdminRoutes(router, config.AdminService.Token)
+	}
+
+	if config.HumanClient.Enable {
+		router.GET(config.HumanClient.Path, func(c *gin.Context) {
+			c.Data(200, "text/html; charset=utf-8", web.HumanClient)
+		})
+	}
+
+	if len(s.pendingGames) > 0 {
+		time.AfterFunc(config.Checkpoint.ResumeTimeout, func() {
+			s.abortPendingGames("再起動後、制限時間内に全てのエージェントが再接続しませんでした")
+		})
+	}
+
+	go func() {
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		for sig := range reload {
+			slog.Info("シグナルを受信しました", "signal", sig)
+			s.Reload()
+		}
+	}()
+
 	go func() {
 		trap := make(chan os.Signal, 1)
-		signal.Notify(trap, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGINT)
+		signal.Notify(trap, syscall.SIGTERM, syscall.SIGINT)
 		sig := <-trap
 		slog.Info("シグナルを受信しました", "signal", sig)
-		s.signaled = true
-		s.gracefullyShutdown()
-		os.Exit(0)
+		s.Drain()
 	}()
 
-	slog.Info("サーバを起動しました", "host", s.config.Server.WebSocket.Host, "port", s.config.Server.WebSocket.Port)
-	err := router.Run(s.config.Server.WebSocket.Host + ":" + strconv.Itoa(s.config.Server.WebSocket.Port))
-	if err != nil {
+	s.httpServer = &http.Server{
+		Addr:    config.Server.WebSocket.Host + ":" + strconv.Itoa(config.Server.WebSocket.Port),
+		Handler: router,
+	}
+	slog.Info("サーバを起動しました", "host", config.Server.WebSocket.Host, "port", config.Server.WebSocket.Port)
+	err := s.httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		slog.Error("サーバの起動に失敗しました", "error", err)
 		return
 	}
+	<-s.drain.done
 }
 
-func (s *Server) gracefullyShutdown() {
-	for {
-		isFinished := true
-		s.mu.RLock()
-		for _, game := range s.games {
-			if !game.IsFinished {
-				isFinished = false
-				break
-			}
-		}
-		s.mu.RUnlock()
-		if isFinished {
-			break
+// 設定は Reload で置き換えられるため、s.mu を保持していない箇所ではこのコピーを使用する
+func (s *Server) currentConfig() model.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+func (s *Server) SetConfigLoader(loader func() (*model.Config, error)) {
+	s.configLoader = loader
+}
+
+// 進行中のゲームは作成時の設定を保持し、新しいゲームから再読み込みした設定を使用する
+func (s *Server) Reload() error {
+	if s.configLoader == nil {
+		err := errors.New("設定の再読み込みが有効ではありません")
+		slog.Error("設定の再読み込みに失敗しました", "error", err)
+		return err
+	}
+	config, err := s.configLoader()
+	if err != nil {
+		slog.Error("設定の再読み込みに失敗しました", "error", err)
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		slog.Error("設定の再読み込みに失敗しました", "error", err)
+		return err
+	}
+	gameSettings, err := model.NewSettings(*config)
+	if err != nil {
+		slog.Error("設定の再読み込みに失敗しました", "error", err)
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := restartRequiredChanges(s.config, *config); err != nil {
+		slog.Error("設定の再読み込みに失敗しました", "error", err)
+		return err
+	}
+	s.config = *config
+	s.gameSettings = gameSettings
+	if s.matchOptimizer != nil {
+		s.matchOptimizer.setInfiniteLoop(config.MatchOptimizer.InfiniteLoop)
+	}
+	slog.Info("設定を再読み込みしました")
+	return nil
+}
+
+func restartRequiredChanges(current model.Config, next model.Config) error {
+	fields := []struct {
+		name    string
+		current interface{}
+		next    interface{}
+	}{
+		{"server", current.Server, next.Server},
+		{"game.agent_count", current.Game.AgentCount, next.Game.AgentCount},
+		{"human_client.enable", current.HumanClient.Enable, next.HumanClient.Enable},
+		{"human_client.path", current.HumanClient.Path, next.HumanClient.Path},
+		{"analysis_service", current.AnalysisService, next.AnalysisService},
+		{"api_service", current.ApiService, next.ApiService},
+		{"deprecated_log_service", current.DeprecatedLogService, next.DeprecatedLogService},
+		{"admin_service", current.AdminService, next.AdminService},
+		{"metrics_service", current.MetricsService, next.MetricsService},
+		{"match_optimizer.enable", current.MatchOptimizer.Enable, next.MatchOptimizer.Enable},
+		{"match_optimizer.team_count", current.MatchOptimizer.TeamCount, next.MatchOptimizer.TeamCount},
+		{"match_optimizer.game_count", current.MatchOptimizer.GameCount, next.MatchOptimizer.GameCount},
+		{"match_optimizer.output_path", current.MatchOptimizer.OutputPath, next.MatchOptimizer.OutputPath},
+		{"checkpoint", current.Checkpoint, next.Checkpoint},
+	}
+	errs := make([]error, 0)
+	for _, field := range fields {
+		if !reflect.DeepEqual(field.current, field.next) {
+			errs = append(errs, model.ConfigError{Field: field.name, Message: "変更を反映するにはサーバの再起動が必要です"})
 		}
-		time.Sleep(15 * time.Second)
 	}
-	slog.Info("全てのゲームが終了しました")
+	return errors.Join(errs...)
 }
 
 func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
-	if s.signaled {
-		slog.Warn("シグナルを受信したため、新しい接続を受け付けません")
+	if s.drain.active.Load() {
+		slog.Warn("ドレイン中のため、新しい接続を受け付けません")
+		w.WriteHeader(http.StatusServiceUnavailable)
 		return
 	}
 	ws, err := s.upgrader.Upgrade(w, r, nil)
@@ -139,8 +258,30 @@ func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
 		slog.Error("クライアントの接続に失敗しました", "error", err)
 		return
 	}
+	config := s.currentConfig()
+	if config.Server.Heartbeat.Enable {
+		connection.Receiver.StartHeartbeat(config.Server.Heartbeat.Interval, config.Server.Heartbeat.Timeout)
+	}
+	// 人間のプレイヤー向けのタイムアウトは、human_client.teams に含まれるチームのみに適用する
+	if config.HumanClient.Enable && r.URL.Query().Get("human") == "true" {
+		if slices.Contains(config.HumanClient.Teams, connection.Team) {
+			connection.IsHuman = true
+			slog.Info("人間のプレイヤーとして接続しました", "team", connection.Team, "name", connection.Name)
+		} else {
+			slog.Warn("人間のプレイヤーとして参加できないチームのため、エージェントとして扱います", "team", connection.Team, "name", connection.Name)
+		}
+	}
+	if s.resumeGame(*connection) {
+		return
+	}
 	s.waitingRoom.AddConnection(connection.Team, *connection)
+	s.matchGame()
+}
 
+func (s *Server) matchGame() {
+	if s.drain.active.Load() {
+		return
+	}
 	s.mu.Lock()
 	var game *logic.Game
 	if s.config.MatchOptimizer.Enable {
@@ -154,7 +295,8 @@ func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
 			s.mu.Unlock()
 			return
 		}
-		game = logic.NewGameWithRole(&s.config, s.gameSettings, roleMapConns)
+		config := s.config
+		game = logic.NewGameWithRole(&config, s.gameSettings, roleMapConns)
 	} else {
 		connections, err := s.waitingRoom.GetConnections()
 		if err != nil {
@@ -162,20 +304,25 @@ func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
 			s.mu.Unlock()
 			return
 		}
-		game = logic.NewGame(&s.config, s.gameSettings, connections)
-	}
-	if s.analysisService != nil {
-		game.SetAnalysisService(s.analysisService)
+		config := s.config
+		game = logic.NewGame(&config, s.gameSettings, connections)
 	}
-	if s.deprecatedLogService != nil {
-		game.SetDeprecatedLogService(s.deprecatedLogService)
+	s.startGame(game)
+	s.mu.Unlock()
+}
+
+// s.mu を保持した状態で呼び出す
+// ゲームの終了後は s.config が再読み込みされている可能性があるため、ゲームが保持する設定を参照する
+func (s *Server) startGame(game *logic.Game) {
+	game.SetEventBus(s.eventBus)
+	if game.Config.Checkpoint.Enable {
+		game.SetCheckpointDir(game.Config.Checkpoint.OutputDir)
 	}
 	s.games = append(s.games, game)
-	s.mu.Unlock()
 
 	go func() {
 		winSide := game.Start()
-		if s.config.MatchOptimizer.Enable {
+		if game.Config.MatchOptimizer.Enable && !game.Aborted() {
 			s.mu.Lock()
 			defer s.mu.Unlock()
 			if winSide != model.T_NONE {
@@ -29,11 +29,128 @@ func (wr *WaitingRoom) AddConnection(team string, connection model.Connection) {
 	defer wr.mu.Unlock()
 	wr.connections[team] = append(wr.connections[team], connection)
 	slog.Info("新しいクライアントが待機部屋に追加されました", "team", team, "remote_addr", connection.Conn.RemoteAddr().String())
+	go func() {
+		<-connection.Receiver.Done()
+		wr.mu.Lock()
+		defer wr.mu.Unlock()
+		wr.pruneClosedConnections()
+	}()
+}
+
+// マッチングの前に切断された接続を取り除き、切断された接続でゲームが開始されないようにする
+func (wr *WaitingRoom) pruneClosedConnections() {
+	for team, conns := range wr.connections {
+		alive := make([]model.Connection, 0, len(conns))
+		for _, conn := range conns {
+			if conn.Receiver.IsAlive() {
+				alive = append(alive, conn)
+				continue
+			}
+			slog.Warn("切断された接続を待機部屋から削除しました", "team", team, "name", conn.Name, "error", conn.Receiver.Err())
+		}
+		if len(alive) == 0 {
+			delete(wr.connections, team)
+		} else {
+			wr.connections[team] = alive
+		}
+	}
+}
+
+func (wr *WaitingRoom) CountByTeam() map[string]int {
+	wr.mu.RLock()
+	defer wr.mu.RUnlock()
+	counts := make(map[string]int)
+	for team, conns := range wr.connections {
+		counts[team] = len(conns)
+	}
+	return counts
+}
+
+func (wr *WaitingRoom) GetConnectionList() map[string][]map[string]string {
+	wr.mu.RLock()
+	defer wr.mu.RUnlock()
+	connections := make(map[string][]map[string]string)
+	for team, conns := range wr.connections {
+		for _, conn := range conns {
+			connections[team] = append(connections[team], map[string]string{
+				"name":        conn.Name,
+				"remote_addr": conn.Conn.RemoteAddr().String(),
+			})
+		}
+	}
+	return connections
+}
+
+func (wr *WaitingRoom) RemoveConnection(team string, name string) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	for i, conn := range wr.connections[team] {
+		if conn.Name == name {
+			conn.Conn.Close()
+			wr.connections[team] = append(wr.connections[team][:i], wr.connections[team][i+1:]...)
+			if len(wr.connections[team]) == 0 {
+				delete(wr.connections, team)
+			}
+			slog.Info("待機部屋から接続を削除しました", "team", team, "name", name)
+			return nil
+		}
+	}
+	return errors.New("待機部屋に接続が見つかりません")
+}
+
+func (wr *WaitingRoom) RemoveAllConnections() []model.Connection {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	connections := make([]model.Connection, 0)
+	for _, conns := range wr.connections {
+		connections = append(connections, conns...)
+	}
+	wr.connections = make(map[string][]model.Connection)
+	return connections
+}
+
+func (wr *WaitingRoom) GetAnyConnections() ([]model.Connection, error) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	wr.pruneClosedConnections()
+
+	total := 0
+	teams := make([]string, 0, len(wr.connections))
+	for team, conns := range wr.connections {
+		total += len(conns)
+		teams = append(teams, team)
+	}
+	if total < wr.agentCount {
+		return nil, errors.New("待機部屋内の接続が不足しています")
+	}
+	rand.Shuffle(len(teams), func(i, j int) {
+		teams[i], teams[j] = teams[j], teams[i]
+	})
+	connections := []model.Connection{}
+	for len(connections) < wr.agentCount {
+		for _, team := range teams {
+			if len(connections) >= wr.agentCount {
+				break
+			}
+			conns := wr.connections[team]
+			if len(conns) == 0 {
+				continue
+			}
+			connections = append(connections, conns[0])
+			wr.connections[team] = conns[1:]
+			if len(wr.connections[team]) == 0 {
+				delete(wr.connections, team)
+			}
+		}
+	}
+	slog.Info("待機部屋内の接続で強制的にマッチを作成しました")
+	return connections, nil
 }
 
 func (wr *WaitingRoom) GetConnectionsWithMatchOptimizer(matches []map[model.Role][]string) (map[model.Role][]model.Connection, error) {
 	wr.mu.Lock()
 	defer wr.mu.Unlock()
+	wr.pruneClosedConnections()
 	var roleMapConns = make(map[model.Role][]model.Connection)
 
 	if len(matches) == 0 {
@@ -80,6 +197,7 @@ func (wr *WaitingRoom) GetConnectionsWithMatchOptimizer(matches []map[model.Role
 func (wr *WaitingRoom) GetConnections() ([]model.Connection, error) {
 	wr.mu.Lock()
 	defer wr.mu.Unlock()
+	wr.pruneClosedConnections()
 
 	connections := []model.Connection{}
 	ready := false
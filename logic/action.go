@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log/slog"
 	"math/rand"
+	"slices"
+	"strings"
 
 	"github.com/kano-lab/aiwolf-nlp-server/model"
 	"github.com/kano-lab/aiwolf-nlp-server/util"
@@ -11,36 +13,44 @@ import (
 
 func (g *Game) doExecution() {
 	slog.Info("追放フェーズを開始します", "id", g.ID, "day", g.CurrentDay)
-	var executed *model.Agent
+	var executed []model.Agent
+	if g.Settings.IsVoteDeclaration {
+		g.executeVoteDeclaration()
+	}
 	candidates := make([]model.Agent, 0)
 	for i := 0; i < g.Settings.MaxRevote; i++ {
 		g.executeVote()
 		candidates = g.getVotedCandidates(g.GameStatuses[g.CurrentDay].Votes)
 		if len(candidates) == 1 {
-			executed = &candidates[0]
+			executed = candidates
 			break
 		}
+		if g.Settings.IsRunoffVote && len(candidates) > 1 {
+			g.GameStatuses[g.CurrentDay].VoteCandidates = candidates
+			slog.Info("同票のため、決選投票を行います", "id", g.ID, "candidates", candidates)
+		}
 	}
+	g.GameStatuses[g.CurrentDay].VoteCandidates = nil
 	if executed == nil && len(candidates) > 0 {
-		rand := util.SelectRandomAgent(candidates)
-		executed = &rand
+		executed = g.breakTie(model.R_VOTE, g.Settings.VoteTieBreak, candidates)
 	}
-	if executed != nil {
-		g.GameStatuses[g.CurrentDay].StatusMap[*executed] = model.S_DEAD
-		g.GameStatuses[g.CurrentDay].ExecutedAgent = executed
-		slog.Info("追放結果を設定しました", "id", g.ID, "agent", executed.String())
-
-		g.GameStatuses[g.CurrentDay].MediumResult = &model.Judge{
-			Day:    g.GameStatuses[g.CurrentDay].Day,
-			Agent:  *executed,
-			Target: *executed,
-			Result: executed.Role.Species,
-		}
-		if g.DeprecatedLogService != nil {
-			g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,execute,%d,%s", g.CurrentDay, executed.Idx, executed.Role.Name))
+	for _, agent := range executed {
+		g.GameStatuses[g.CurrentDay].StatusMap[agent] = model.S_DEAD
+		g.GameStatuses[g.CurrentDay].ExecutedAgents = append(g.GameStatuses[g.CurrentDay].ExecutedAgents, agent)
+		if g.GameStatuses[g.CurrentDay].ExecutedAgent == nil {
+			g.GameStatuses[g.CurrentDay].ExecutedAgent = &agent
+			g.GameStatuses[g.CurrentDay].MediumResult = &model.Judge{
+				Day:    g.GameStatuses[g.CurrentDay].Day,
+				Agent:  agent,
+				Target: agent,
+				Result: agent.Role.Species,
+			}
+			slog.Info("霊能結果を設定しました", "id", g.ID, "target", agent.String(), "result", agent.Role.Species)
 		}
-		slog.Info("霊能結果を設定しました", "id", g.ID, "target", executed.String(), "result", executed.Role.Species)
-	} else {
+		g.publish(model.ExecutedEvent{EventHeader: g.header(), Agent: agent})
+		slog.Info("追放結果を設定しました", "id", g.ID, "agent", agent.String())
+	}
+	if len(executed) == 0 {
 		slog.Warn("追放対象がいないため、追放結果を設定しません", "id", g.ID)
 	}
 	slog.Info("追放フェーズを終了します", "id", g.ID, "day", g.CurrentDay)
@@ -48,7 +58,7 @@ func (g *Game) doExecution() {
 
 func (g *Game) doAttack() {
 	slog.Info("襲撃フェーズを開始します", "id", g.ID, "day", g.CurrentDay)
-	var attacked *model.Agent
+	var attacked []model.Agent
 	werewolfs := g.getAliveWerewolves()
 	if len(werewolfs) > 0 {
 		candidates := make([]model.Agent, 0)
@@ -56,37 +66,81 @@ func (g *Game) doAttack() {
 			g.executeAttackVote()
 			candidates = g.getAttackVotedCandidates(g.GameStatuses[g.CurrentDay].AttackVotes)
 			if len(candidates) == 1 {
-				attacked = &candidates[0]
+				attacked = candidates
 				break
 			}
 		}
-		if attacked == nil && !g.Settings.IsEnableNoAttack && len(candidates) > 0 {
-			rand := util.SelectRandomAgent(candidates)
-			attacked = &rand
+		if attacked == nil && len(candidates) > 0 {
+			attacked = g.breakTie(model.R_ATTACK, g.Settings.AttackTieBreak, candidates)
 		}
 
-		if attacked != nil && !g.isGuarded(attacked) {
-			g.GameStatuses[g.CurrentDay].StatusMap[*attacked] = model.S_DEAD
-			g.GameStatuses[g.CurrentDay].AttackedAgent = attacked
-			if g.DeprecatedLogService != nil {
-				g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,attack,%d,true", g.CurrentDay, attacked.Idx))
-			}
-			slog.Info("襲撃結果を設定しました", "id", g.ID, "agent", attacked.String())
-		} else if attacked != nil {
-			if g.DeprecatedLogService != nil {
-				g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,attack,%d,false", g.CurrentDay, attacked.Idx))
-			}
-			slog.Info("護衛されたため、襲撃結果を設定しません", "id", g.ID, "agent", attacked.String())
-		} else {
-			if g.DeprecatedLogService != nil {
-				g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,attack,-1,true", g.CurrentDay))
+		for _, agent := range attacked {
+			if !g.isGuarded(&agent) {
+				g.GameStatuses[g.CurrentDay].StatusMap[agent] = model.S_DEAD
+				g.GameStatuses[g.CurrentDay].AttackedAgents = append(g.GameStatuses[g.CurrentDay].AttackedAgents, agent)
+				if g.GameStatuses[g.CurrentDay].AttackedAgent == nil {
+					g.GameStatuses[g.CurrentDay].AttackedAgent = &agent
+				}
+				g.publish(model.AttackedEvent{EventHeader: g.header(), Agent: &agent, Succeeded: true})
+				slog.Info("襲撃結果を設定しました", "id", g.ID, "agent", agent.String())
+			} else {
+				g.publish(model.AttackedEvent{EventHeader: g.header(), Agent: &agent, Succeeded: false})
+				slog.Info("護衛されたため、襲撃結果を設定しません", "id", g.ID, "agent", agent.String())
 			}
+		}
+		if len(attacked) == 0 {
+			g.publish(model.AttackedEvent{EventHeader: g.header(), Agent: nil, Succeeded: true})
 			slog.Info("襲撃対象がいないため、襲撃結果を設定しません", "id", g.ID)
 		}
 	}
 	slog.Info("襲撃フェーズを終了します", "id", g.ID, "day", g.CurrentDay)
 }
 
+func (g *Game) breakTie(request model.Request, tieBreak model.TieBreak, candidates []model.Agent) []model.Agent {
+	slog.Info("同票のため、タイブレークを行います", "id", g.ID, "request", request, "tieBreak", tieBreak, "candidates", candidates)
+	var resolved []model.Agent
+	switch tieBreak {
+	case model.TB_NONE:
+		resolved = []model.Agent{}
+	case model.TB_ALL:
+		resolved = candidates
+	case model.TB_RUNOFF:
+		var runoffCandidates []model.Agent
+		if request == model.R_VOTE {
+			g.GameStatuses[g.CurrentDay].VoteCandidates = candidates
+			g.executeVote()
+			g.GameStatuses[g.CurrentDay].VoteCandidates = nil
+			runoffCandidates = g.getVotedCandidates(g.GameStatuses[g.CurrentDay].Votes)
+		} else {
+			g.GameStatuses[g.CurrentDay].AttackVoteCandidates = candidates
+			g.executeAttackVote()
+			g.GameStatuses[g.CurrentDay].AttackVoteCandidates = nil
+			runoffCandidates = g.getAttackVotedCandidates(g.GameStatuses[g.CurrentDay].AttackVotes)
+		}
+		if len(runoffCandidates) == 1 {
+			resolved = runoffCandidates
+		} else {
+			pool := candidates
+			if len(runoffCandidates) > 1 {
+				pool = runoffCandidates
+			}
+			slog.Warn("決選投票でも同票となったため、ランダムに選択します", "id", g.ID, "candidates", pool)
+			resolved = []model.Agent{util.SelectRandomAgent(pool)}
+		}
+	default:
+		resolved = []model.Agent{util.SelectRandomAgent(candidates)}
+	}
+	slog.Info("タイブレークの結果を設定しました", "id", g.ID, "request", request, "tieBreak", tieBreak, "resolved", resolved)
+	g.publish(model.TieBrokenEvent{
+		EventHeader: g.header(),
+		Request:     request,
+		TieBreak:    tieBreak,
+		Candidates:  candidates,
+		Resolved:    resolved,
+	})
+	return resolved
+}
+
 func (g *Game) isGuarded(attacked *model.Agent) bool {
 	if g.GameStatuses[g.CurrentDay].Guard == nil {
 		return false
@@ -96,6 +150,7 @@ func (g *Game) isGuarded(attacked *model.Agent) bool {
 
 func (g *Game) doDivine() {
 	slog.Info("占いフェーズを開始します", "id", g.ID, "day", g.CurrentDay)
+	g.enterPhase(model.R_DIVINE.Type)
 	for _, agent := range g.getAliveAgents() {
 		if agent.Role == model.R_SEER {
 			g.conductDivination(agent)
@@ -108,9 +163,14 @@ func (g *Game) doDivine() {
 func (g *Game) conductDivination(agent *model.Agent) {
 	slog.Info("占いアクションを開始します", "id", g.ID, "agent", agent.String())
 	target, err := g.findTargetByRequest(agent, model.R_DIVINE)
+	decision := model.D_AGENT
 	if err != nil {
-		slog.Warn("占い対象が見つからなかったため、占い結果を設定しません", "id", g.ID)
-		return
+		target = g.fallbackTarget(agent, model.R_DIVINE, nil, err)
+		if target == nil {
+			slog.Warn("占い対象が見つからなかったため、占い結果を設定しません", "id", g.ID)
+			return
+		}
+		decision = model.D_SERVER_SUBSTITUTED
 	}
 	if !g.isAlive(target) {
 		slog.Warn("占い対象が死亡しているため、占い結果を設定しません", "id", g.ID, "target", target.String())
@@ -126,14 +186,14 @@ func (g *Game) conductDivination(agent *model.Agent) {
 		Target: *target,
 		Result: target.Role.Species,
 	}
-	if g.DeprecatedLogService != nil {
-		g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,divine,%d,%d,%s", g.CurrentDay, agent.Idx, target.Idx, target.Role.Species))
-	}
+	g.recordTarget(agent, model.R_DIVINE, target)
+	g.publish(model.DivinedEvent{EventHeader: g.header(), Judge: *g.GameStatuses[g.CurrentDay].DivineResult, Decision: decision})
 	slog.Info("占い結果を設定しました", "id", g.ID, "target", target.String(), "result", target.Role.Species)
 }
 
 func (g *Game) doGuard() {
 	slog.Info("護衛フェーズを開始します", "id", g.ID, "day", g.CurrentDay)
+	g.enterPhase(model.R_GUARD.Type)
 	for _, agent := range g.getAliveAgents() {
 		if agent.Role == model.R_BODYGUARD {
 			g.conductGuard(agent)
@@ -145,9 +205,14 @@ func (g *Game) doGuard() {
 func (g *Game) conductGuard(agent *model.Agent) {
 	slog.Info("護衛アクションを実行します", "id", g.ID, "agent", agent.String())
 	target, err := g.findTargetByRequest(agent, model.R_GUARD)
+	decision := model.D_AGENT
 	if err != nil {
-		slog.Warn("護衛対象が見つからなかったため、護衛対象を設定しません", "id", g.ID)
-		return
+		target = g.fallbackTarget(agent, model.R_GUARD, nil, err)
+		if target == nil {
+			slog.Warn("護衛対象が見つからなかったため、護衛対象を設定しません", "id", g.ID)
+			return
+		}
+		decision = model.D_SERVER_SUBSTITUTED
 	}
 	if !g.isAlive(target) {
 		slog.Warn("護衛対象が死亡しているため、護衛対象を設定しません", "id", g.ID, "target", target.String())
@@ -162,51 +227,70 @@ func (g *Game) conductGuard(agent *model.Agent) {
 		Agent:  *agent,
 		Target: *target,
 	}
-	if g.DeprecatedLogService != nil {
-		g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,guard,%d,%d,%s", g.CurrentDay, agent.Idx, target.Idx, target.Role.Name))
-	}
+	g.recordTarget(agent, model.R_GUARD, target)
+	g.publish(model.GuardedEvent{EventHeader: g.header(), Guard: *g.GameStatuses[g.CurrentDay].Guard, Decision: decision})
 	slog.Info("護衛対象を設定しました", "id", g.ID, "target", target.String())
 }
 
+func (g *Game) executeVoteDeclaration() {
+	slog.Info("投票宣言アクションを開始します", "id", g.ID, "day", g.CurrentDay)
+	g.enterPhase(model.R_DECLARE_VOTE.Type)
+	g.GameStatuses[g.CurrentDay].DeclaredVotes = make([]model.Vote, 0)
+	g.collectVotes(model.R_DECLARE_VOTE, g.getAliveAgents(), &g.GameStatuses[g.CurrentDay].DeclaredVotes, nil)
+}
+
 func (g *Game) executeVote() {
 	slog.Info("投票アクションを開始します", "id", g.ID, "day", g.CurrentDay)
-	g.GameStatuses[g.CurrentDay].Votes = g.collectVotes(model.R_VOTE, g.getAliveAgents())
+	g.enterPhase(model.R_VOTE.Type)
+	agents := g.getAliveAgents()
+	if g.Settings.VoteMode == model.VM_SEQUENTIAL {
+		rand.Shuffle(len(agents), func(i, j int) {
+			agents[i], agents[j] = agents[j], agents[i]
+		})
+	}
+	g.GameStatuses[g.CurrentDay].Votes = make([]model.Vote, 0)
+	g.collectVotes(model.R_VOTE, agents, &g.GameStatuses[g.CurrentDay].Votes, g.GameStatuses[g.CurrentDay].VoteCandidates)
 }
 
 func (g *Game) executeAttackVote() {
 	slog.Info("襲撃投票アクションを開始します", "id", g.ID, "day", g.CurrentDay)
-	g.GameStatuses[g.CurrentDay].AttackVotes = g.collectVotes(model.R_ATTACK, g.getAliveWerewolves())
+	g.enterPhase(model.R_ATTACK.Type)
+	g.GameStatuses[g.CurrentDay].AttackVotes = make([]model.Vote, 0)
+	g.collectVotes(model.R_ATTACK, g.getAliveWerewolves(), &g.GameStatuses[g.CurrentDay].AttackVotes, g.GameStatuses[g.CurrentDay].AttackVoteCandidates)
 }
 
-func (g *Game) collectVotes(request model.Request, agents []*model.Agent) []model.Vote {
-	votes := make([]model.Vote, 0)
-	if request != model.R_VOTE && request != model.R_ATTACK {
-		return votes
+func (g *Game) collectVotes(request model.Request, agents []*model.Agent, votes *[]model.Vote, candidates []model.Agent) {
+	if request != model.R_VOTE && request != model.R_ATTACK && request != model.R_DECLARE_VOTE {
+		return
 	}
 	for _, agent := range agents {
 		target, err := g.findTargetByRequest(agent, request)
+		decision := model.D_AGENT
 		if err != nil {
-			continue
+			target = g.fallbackTarget(agent, request, candidates, err)
+			if target == nil {
+				continue
+			}
+			decision = model.D_SERVER_SUBSTITUTED
 		}
 		if !g.isAlive(target) {
 			slog.Warn("投票対象が死亡しているため、投票を無視します", "id", g.ID, "agent", agent.String(), "target", target.String())
 			continue
 		}
-		votes = append(votes, model.Vote{
+		if len(candidates) > 0 && !slices.Contains(candidates, *target) {
+			slog.Warn("投票対象が決選投票の候補ではないため、投票を無視します", "id", g.ID, "agent", agent.String(), "target", target.String())
+			continue
+		}
+		vote := model.Vote{
 			Day:    g.GameStatuses[g.CurrentDay].Day,
 			Agent:  *agent,
 			Target: *target,
-		})
-		if g.DeprecatedLogService != nil {
-			if request == model.R_VOTE {
-				g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,vote,%d,%d", g.CurrentDay, agent.Idx, target.Idx))
-			} else {
-				g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,attackVote,%d,%d", g.CurrentDay, agent.Idx, target.Idx))
-			}
 		}
+		*votes = append(*votes, vote)
+		g.recordTarget(agent, request, target)
+		g.publish(model.VoteCastEvent{EventHeader: g.header(), Request: request, Vote: vote, Decision: decision})
 		slog.Info("投票を受信しました", "id", g.ID, "agent", agent.String(), "target", target.String())
 	}
-	return votes
 }
 
 func (g *Game) doWhisper() {
@@ -255,12 +339,13 @@ func (g *Game) conductCommunication(request model.Request) {
 	idx := 0
 
 	for i := 0; i < maxTurn; i++ {
+		g.enterPhase(fmt.Sprintf("%s[%d]", request.Type, i))
 		cnt := false
 		for _, agent := range agents {
 			if remainMap[*agent] <= 0 {
 				continue
 			}
-			text := g.getTalkWhisperText(agent, request, skipMap, remainMap)
+			text, decision := g.getTalkWhisperText(agent, request, skipMap, remainMap)
 			talk := model.Talk{
 				Idx:   idx,
 				Day:   g.GameStatuses[g.CurrentDay].Day,
@@ -268,6 +353,9 @@ func (g *Game) conductCommunication(request model.Request) {
 				Agent: *agent,
 				Text:  text,
 			}
+			if g.Config.Game.LanguageDetection && text != model.T_OVER && text != model.T_SKIP {
+				g.detectTalkLanguage(&talk, request)
+			}
 			idx++
 			*talkList = append(*talkList, talk)
 			if text != model.T_OVER {
@@ -276,13 +364,7 @@ func (g *Game) conductCommunication(request model.Request) {
 				remainMap[*agent] = 0
 				slog.Info("発言がオーバーであるため、残り発言回数を0にしました", "id", g.ID, "agent", agent.String())
 			}
-			if g.DeprecatedLogService != nil {
-				if request == model.R_TALK {
-					g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,talk,%d,%d,%d,%s", g.CurrentDay, talk.Idx, talk.Turn, talk.Agent.Idx, talk.Text))
-				} else {
-					g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,whisper,%d,%d,%d,%s", g.CurrentDay, talk.Idx, talk.Turn, talk.Agent.Idx, talk.Text))
-				}
-			}
+			g.publish(model.TalkSpokenEvent{EventHeader: g.header(), Request: request, Talk: talk, Decision: decision})
 			slog.Info("発言を受信しました", "id", g.ID, "agent", agent.String(), "text", text, "skip", skipMap[*agent], "remain", remainMap[*agent])
 		}
 		if !cnt {
@@ -291,15 +373,23 @@ func (g *Game) conductCommunication(request model.Request) {
 	}
 }
 
-func (g *Game) getTalkWhisperText(agent *model.Agent, request model.Request, skipMap map[model.Agent]int, remainMap map[model.Agent]int) string {
+func (g *Game) getTalkWhisperText(agent *model.Agent, request model.Request, skipMap map[model.Agent]int, remainMap map[model.Agent]int) (string, model.Decision) {
 	text, err := g.requestToAgent(agent, request)
+	decision := model.D_AGENT
 	if text == model.T_FORCE_SKIP {
 		text = model.T_SKIP
 		slog.Warn("クライアントから強制スキップが指定されたため、発言をスキップに置換しました", "id", g.ID, "agent", agent.String())
 	}
 	if err != nil {
 		text = model.T_FORCE_SKIP
+		decision = model.D_SERVER_SUBSTITUTED
 		slog.Warn("リクエストの送受信に失敗したため、発言をスキップに置換しました", "id", g.ID, "agent", agent.String())
+	} else if g.TalkValidator != nil && text != model.T_OVER && text != model.T_SKIP {
+		// 検証によって書き換えた発言は、エージェント自身の発言として記録しない
+		if validated := g.validateTalkText(agent, request, text); validated != text {
+			text = validated
+			decision = model.D_SERVER_SUBSTITUTED
+		}
 	}
 	remainMap[*agent]--
 	if _, exists := skipMap[*agent]; !exists {
@@ -321,5 +411,53 @@ func (g *Game) getTalkWhisperText(agent *model.Agent, request model.Request, ski
 		skipMap[*agent] = 0
 		slog.Info("発言がオーバーもしくはスキップではないため、スキップ回数をリセットしました", "id", g.ID, "agent", agent.String())
 	}
-	return text
+	return text, decision
+}
+
+func (g *Game) validateTalkText(agent *model.Agent, request model.Request, text string) string {
+	validated, violations := g.TalkValidator.Validate(text)
+	if len(violations) == 0 {
+		return validated
+	}
+	action := model.VA_TRUNCATE
+	for _, violation := range violations {
+		if !violation.IsSanitization() {
+			action = g.TalkValidator.Action()
+			break
+		}
+	}
+	switch action {
+	case model.VA_SKIP:
+		validated = model.T_FORCE_SKIP
+	case model.VA_ERROR:
+		validated = model.T_FORCE_SKIP
+		agent.HasError = true
+	}
+	if strings.TrimSpace(validated) == "" {
+		validated = model.T_FORCE_SKIP
+	}
+	slog.Warn("発言の検証に違反したため、発言を置換しました", "id", g.ID, "agent", agent.String(), "violations", violations, "action", action)
+	g.publish(model.TalkViolatedEvent{
+		EventHeader: g.header(),
+		Agent:       *agent,
+		Request:     request,
+		Violations:  violations,
+		Action:      action,
+		Text:        text,
+	})
+	return validated
+}
+
+func (g *Game) detectTalkLanguage(talk *model.Talk, request model.Request) {
+	talk.Language = util.DetectLanguage(talk.Text)
+	if talk.Language == "" || g.Settings.Language == "" || talk.Language == g.Settings.Language {
+		return
+	}
+	slog.Warn("発言の言語がゲームの言語と一致しません", "id", g.ID, "agent", talk.Agent.String(), "language", talk.Language, "expected", g.Settings.Language)
+	g.publish(model.LanguageMismatchedEvent{
+		EventHeader: g.header(),
+		Request:     request,
+		Talk:        *talk,
+		Expected:    g.Settings.Language,
+	})
 }
@@ -0,0 +1,208 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/util"
+)
+
+type Checkpoint struct {
+	GameID       string                  `json:"game_id"`
+	Day          int                     `json:"day"`
+	Phase        string                  `json:"phase,omitempty"`
+	Agents       []CheckpointAgent       `json:"agents"`
+	GameStatuses map[int]json.RawMessage `json:"game_statuses"`
+	UpdatedAt    time.Time               `json:"updated_at"`
+	AbortReason  string                  `json:"abort_reason,omitempty"`
+	path         string
+}
+
+type CheckpointAgent struct {
+	Idx     int            `json:"idx"`
+	Team    string         `json:"team"`
+	Name    string         `json:"name"`
+	Role    model.Role     `json:"role"`
+	Persona *model.Persona `json:"persona,omitempty"`
+}
+
+func (ca *CheckpointAgent) UnmarshalJSON(data []byte) error {
+	type Alias CheckpointAgent
+	aux := &struct {
+		*Alias
+		Role string `json:"role"`
+	}{
+		Alias: (*Alias)(ca),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	ca.Role = model.RoleFromString(aux.Role)
+	return nil
+}
+
+// 中断されたチェックポイントは記録として残すため、読み込まない
+func LoadCheckpoints(dir string) ([]*Checkpoint, error) {
+	filePaths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	checkpoints := make([]*Checkpoint, 0)
+	for _, filePath := range filePaths {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			slog.Warn("チェックポイントの読み込みに失敗しました", "file", filePath, "error", err)
+			continue
+		}
+		var checkpoint Checkpoint
+		if err := json.Unmarshal(data, &checkpoint); err != nil {
+			slog.Warn("チェックポイントの読み込みに失敗しました", "file", filePath, "error", err)
+			continue
+		}
+		if checkpoint.AbortReason != "" {
+			continue
+		}
+		checkpoint.path = filePath
+		checkpoints = append(checkpoints, &checkpoint)
+		slog.Info("チェックポイントを読み込みました", "id", checkpoint.GameID, "day", checkpoint.Day, "phase", checkpoint.Phase)
+	}
+	return checkpoints, nil
+}
+
+func (c *Checkpoint) RoleTeamNames() map[model.Role][]string {
+	roleTeamNamesMap := make(map[model.Role][]string)
+	for _, a := range c.Agents {
+		roleTeamNamesMap[a.Role] = append(roleTeamNamesMap[a.Role], a.Team)
+	}
+	return roleTeamNamesMap
+}
+
+func (c *Checkpoint) Abort(reason string) error {
+	c.AbortReason = reason
+	slog.Warn("チェックポイントのゲームを中断しました", "id", c.GameID, "reason", reason)
+	return c.save()
+}
+
+// 書き込み途中でクラッシュしても以前のチェックポイントが残るように、一時ファイルから置き換える
+func (c *Checkpoint) save() error {
+	c.UpdatedAt = time.Now()
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, c.path)
+}
+
+func NewGameFromCheckpoint(config *model.Config, settings *model.Settings, checkpoint *Checkpoint, conns map[int]model.Connection) (*Game, error) {
+	agents := make([]*model.Agent, 0, len(checkpoint.Agents))
+	for _, a := range checkpoint.Agents {
+		conn, exists := conns[a.Idx]
+		if !exists {
+			return nil, errors.New("再接続していないエージェントがあります")
+		}
+		agent, err := model.NewAgent(a.Idx, a.Role, conn)
+		if err != nil {
+			return nil, err
+		}
+		agent.Persona = a.Persona
+		agents = append(agents, agent)
+	}
+	gameStatuses := make(map[int]*model.GameStatus)
+	for day, data := range checkpoint.GameStatuses {
+		gameStatus, err := model.UnmarshalGameStatus(data, agents)
+		if err != nil {
+			return nil, err
+		}
+		gameStatuses[day] = gameStatus
+	}
+	if _, exists := gameStatuses[checkpoint.Day]; !exists {
+		return nil, errors.New("チェックポイントに再開する日のゲームの状態が含まれていません")
+	}
+	if checkpoint.Phase != "" && !slices.Contains(checkpointPhases, checkpoint.Phase) {
+		return nil, errors.New("チェックポイントのフェーズが不正です")
+	}
+	slog.Info("チェックポイントからゲームを再開します", "id", checkpoint.GameID, "day", checkpoint.Day, "phase", checkpoint.Phase)
+	return &Game{
+		Config:            config,
+		ID:                checkpoint.GameID,
+		Settings:          settings,
+		Agents:            agents,
+		CurrentDay:        checkpoint.Day,
+		GameStatuses:      gameStatuses,
+		LastTalkIdxMap:    make(map[*model.Agent]int),
+		LastWhisperIdxMap: make(map[*model.Agent]int),
+		IsFinished:        false,
+		IsStepMode:        config.Game.Debug.StepMode,
+		TalkValidator:     util.NewTalkValidator(*config),
+		pauseCond:         sync.NewCond(&sync.Mutex{}),
+		resumePhase:       checkpoint.Phase,
+	}, nil
+}
+
+func (g *Game) SetCheckpointDir(dir string) {
+	g.checkpointDir = dir
+}
+
+func (g *Game) checkpointPath() string {
+	return filepath.Join(g.checkpointDir, g.ID+".json")
+}
+
+// 各フェーズの開始時のゲームの状態を保存し、再開時はそのフェーズから進行する
+func (g *Game) saveCheckpoint(phase string) {
+	if g.checkpointDir == "" {
+		return
+	}
+	checkpoint := Checkpoint{
+		GameID:       g.ID,
+		Day:          g.CurrentDay,
+		Phase:        phase,
+		Agents:       make([]CheckpointAgent, 0, len(g.Agents)),
+		GameStatuses: make(map[int]json.RawMessage),
+		path:         g.checkpointPath(),
+	}
+	for _, agent := range g.Agents {
+		checkpoint.Agents = append(checkpoint.Agents, CheckpointAgent{
+			Idx:     agent.Idx,
+			Team:    agent.Team,
+			Name:    agent.Name,
+			Role:    agent.Role,
+			Persona: agent.Persona,
+		})
+	}
+	for day, gameStatus := range g.GameStatuses {
+		data, err := json.Marshal(gameStatus)
+		if err != nil {
+			slog.Error("チェックポイントの保存に失敗しました", "id", g.ID, "error", err)
+			return
+		}
+		checkpoint.GameStatuses[day] = data
+	}
+	if err := checkpoint.save(); err != nil {
+		slog.Error("チェックポイントの保存に失敗しました", "id", g.ID, "error", err)
+		return
+	}
+	slog.Info("チェックポイントを保存しました", "id", g.ID, "day", g.CurrentDay, "phase", phase, "path", checkpoint.path)
+}
+
+func (g *Game) removeCheckpoint() {
+	if g.checkpointDir == "" {
+		return
+	}
+	if err := os.Remove(g.checkpointPath()); err != nil && !os.IsNotExist(err) {
+		slog.Warn("チェックポイントの削除に失敗しました", "id", g.ID, "error", err)
+	}
+}
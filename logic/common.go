@@ -14,7 +14,7 @@ func (g *Game) findTargetByRequest(agent *model.Agent, request model.Request) (*
 	if err != nil {
 		return nil, err
 	}
-	target := util.FindAgentByName(g.Agents, name)
+	target := util.FindAgentByName(g.Agents, name, !g.Config.Game.Persona.Enable)
 	if target == nil {
 		return nil, errors.New("対象エージェントが見つかりません")
 	}
@@ -47,6 +47,9 @@ func (g *Game) requestToEveryone(request model.Request) {
 }
 
 func (g *Game) requestToAgent(agent *model.Agent, request model.Request) (string, error) {
+	if g.waitIfPaused(true) && request != model.R_FINISH {
+		return "", errors.New("ゲームが中断されたため、リクエストを送信しません")
+	}
 	info := model.NewInfo(agent, g.GameStatuses[g.CurrentDay], g.GameStatuses[g.CurrentDay-1], g.Settings)
 	var packet model.Packet
 	switch request {
@@ -55,7 +58,10 @@ func (g *Game) requestToAgent(agent *model.Agent, request model.Request) (string
 	case model.R_INITIALIZE, model.R_DAILY_INITIALIZE:
 		g.resetLastIdxMaps()
 		packet = model.Packet{Request: &request, Info: &info, Settings: g.Settings}
-	case model.R_VOTE, model.R_DIVINE, model.R_GUARD:
+		if request == model.R_INITIALIZE {
+			info.Persona = agent.Persona
+		}
+	case model.R_VOTE, model.R_DECLARE_VOTE, model.R_DIVINE, model.R_GUARD:
 		packet = model.Packet{Request: &request, Info: &info}
 	case model.R_DAILY_FINISH, model.R_TALK, model.R_WHISPER, model.R_ATTACK:
 		packet = model.Packet{Request: &request, Info: &info}
@@ -72,13 +78,18 @@ func (g *Game) requestToAgent(agent *model.Agent, request model.Request) (string
 	default:
 		return "", errors.New("一致するリクエストがありません")
 	}
-	if g.AnalysisService != nil {
-		g.AnalysisService.TrackStartRequest(g.ID, *agent, packet)
-	}
-	resp, err := agent.SendPacket(packet, time.Duration(g.Settings.ActionTimeout)*time.Millisecond, time.Duration(g.Settings.ResponseTimeout)*time.Millisecond, g.Config.Game.Timeout.Acceptable)
-	if g.AnalysisService != nil {
-		g.AnalysisService.TrackEndRequest(g.ID, *agent, resp, err)
+	packet.GameID = g.ID
+	packet.RequestID = agent.Receiver.NextRequestID()
+	g.publish(model.RequestStartedEvent{EventHeader: g.header(), Agent: *agent, Packet: packet})
+	start := time.Now()
+	actionTimeout := g.Settings.ActionTimeoutFor(request)
+	responseTimeout := time.Duration(g.Settings.ResponseTimeout) * time.Millisecond
+	if agent.IsHuman {
+		actionTimeout = g.Config.HumanClient.Timeout.Action
+		responseTimeout = g.Config.HumanClient.Timeout.Response
 	}
+	resp, err := agent.SendPacket(packet, actionTimeout, responseTimeout, g.Config.Game.Timeout.Acceptable)
+	g.publish(model.RequestEndedEvent{EventHeader: g.header(), Agent: *agent, RequestID: packet.RequestID, Request: request, Response: resp, Duration: time.Since(start), Error: err})
 	return resp, err
 }
 
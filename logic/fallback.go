@@ -0,0 +1,103 @@
+package logic
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand"
+	"slices"
+
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/util"
+)
+
+// レスポンスの送受信に失敗した場合や対象が見つからない場合に、設定に従って代わりの対象を選択する
+func (g *Game) fallbackTarget(agent *model.Agent, request model.Request, candidates []model.Agent, err error) *model.Agent {
+	if g.Aborted() {
+		return nil
+	}
+	fallback := g.Settings.FallbackMap[request.Type]
+	// 投票のタイムアウトは game.vote.timeout_fallback に従う
+	if errors.Is(err, model.ErrResponseTimeout) && (request == model.R_VOTE || request == model.R_DECLARE_VOTE) {
+		fallback = g.Settings.VoteTimeoutFallback
+	}
+	if fallback == model.FB_ABSTAIN || fallback == "" {
+		slog.Info("代わりの行動が棄権であるため、アクションを行いません", "id", g.ID, "agent", agent.String(), "request", request.Type, "error", err)
+		return nil
+	}
+	targets := util.FilterAgents(g.getAliveAgents(), func(target *model.Agent) bool {
+		if len(candidates) > 0 && !slices.Contains(candidates, *target) {
+			return false
+		}
+		switch request {
+		case model.R_ATTACK:
+			return target.Role.Species != model.S_WEREWOLF
+		case model.R_DIVINE, model.R_GUARD:
+			return target != agent
+		}
+		return fallback != model.FB_EXCLUDE_SELF || target != agent
+	})
+	var target *model.Agent
+	switch fallback {
+	case model.FB_RANDOM, model.FB_EXCLUDE_SELF:
+		if len(targets) > 0 {
+			target = targets[rand.Intn(len(targets))]
+		}
+	case model.FB_LAST:
+		if last := g.lastTarget(agent, request); last != nil && slices.Contains(targets, last) {
+			target = last
+		}
+	}
+	if target == nil {
+		slog.Warn("代わりの対象がないため、アクションを行いません", "id", g.ID, "agent", agent.String(), "request", request.Type, "fallback", fallback)
+		return nil
+	}
+	slog.Warn("レスポンスが得られなかったため、代わりの対象を選択しました", "id", g.ID, "agent", agent.String(), "request", request.Type, "fallback", fallback, "target", target.String(), "error", err)
+	return target
+}
+
+func (g *Game) recordTarget(agent *model.Agent, request model.Request, target *model.Agent) {
+	if g.lastTargets == nil {
+		g.lastTargets = make(map[string]map[*model.Agent]*model.Agent)
+	}
+	if g.lastTargets[request.Type] == nil {
+		g.lastTargets[request.Type] = make(map[*model.Agent]*model.Agent)
+	}
+	g.lastTargets[request.Type][agent] = target
+}
+
+// 同じ種類のリクエストで最後に選択した対象を返す
+// 再投票で上書きされた同じ日の投票も含めるため、受け付けた対象を記録しておき、記録がない場合 (チェックポイントから再開した場合) のみゲームの状態から探す
+func (g *Game) lastTarget(agent *model.Agent, request model.Request) *model.Agent {
+	if target, exists := g.lastTargets[request.Type][agent]; exists {
+		return target
+	}
+	for day := g.CurrentDay; day >= 0; day-- {
+		status, exists := g.GameStatuses[day]
+		if !exists {
+			continue
+		}
+		var votes []model.Vote
+		switch request {
+		case model.R_VOTE:
+			votes = status.Votes
+		case model.R_DECLARE_VOTE:
+			votes = status.DeclaredVotes
+		case model.R_ATTACK:
+			votes = status.AttackVotes
+		case model.R_DIVINE:
+			if status.DivineResult != nil && status.DivineResult.Agent.String() == agent.String() {
+				return util.FindAgentByName(g.Agents, status.DivineResult.Target.String(), false)
+			}
+		case model.R_GUARD:
+			if status.Guard != nil && status.Guard.Agent.String() == agent.String() {
+				return util.FindAgentByName(g.Agents, status.Guard.Target.String(), false)
+			}
+		}
+		for i := len(votes) - 1; i >= 0; i-- {
+			if votes[i].Agent.String() == agent.String() {
+				return util.FindAgentByName(g.Agents, votes[i].Target.String(), false)
+			}
+		}
+	}
+	return nil
+}
@@ -1,8 +1,10 @@
 package logic
 
 import (
-	"fmt"
+	"encoding/json"
 	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/kano-lab/aiwolf-nlp-server/model"
 	"github.com/kano-lab/aiwolf-nlp-server/service"
@@ -11,22 +13,44 @@ import (
 )
 
 type Game struct {
-	Config               *model.Config
-	ID                   string
-	Settings             *model.Settings
-	Agents               []*model.Agent
-	CurrentDay           int
-	GameStatuses         map[int]*model.GameStatus
-	LastTalkIdxMap       map[*model.Agent]int
-	LastWhisperIdxMap    map[*model.Agent]int
-	IsFinished           bool
-	AnalysisService      *service.AnalysisService
-	DeprecatedLogService *service.DeprecatedLogService
+	Config            *model.Config
+	ID                string
+	Settings          *model.Settings
+	Agents            []*model.Agent
+	CurrentDay        int
+	GameStatuses      map[int]*model.GameStatus
+	LastTalkIdxMap    map[*model.Agent]int
+	LastWhisperIdxMap map[*model.Agent]int
+	IsFinished        bool
+	IsAborted         bool
+	IsPaused          bool
+	IsStepMode        bool
+	CurrentPhase      string
+	TalkValidator     *util.TalkValidator
+	EventBus          *service.EventBus
+	pauseCond         *sync.Cond
+	steps             int
+	statusSnapshot    json.RawMessage
+	lastTargets       map[string]map[*model.Agent]*model.Agent
+	checkpointDir     string
+	resumePhase       string
 }
 
+type dayPhase struct {
+	name     string
+	firstDay bool
+	run      func()
+}
+
+// チェックポイントを保存する1日のフェーズ (進行順)
+var checkpointPhases = []string{"day", "night", "execution", "divine", "whisper", "guard", "attack"}
+
 func NewGame(config *model.Config, settings *model.Settings, conns []model.Connection) *Game {
 	id := ulid.Make().String()
 	agents := util.CreateAgents(conns, settings.RoleNumMap)
+	if config.Game.Persona.Enable {
+		util.AssignPersonas(agents, config.Game.Persona.Pool)
+	}
 	gameStatus := model.NewInitializeGameStatus(agents)
 	gameStatuses := make(map[int]*model.GameStatus)
 	gameStatuses[0] = &gameStatus
@@ -41,12 +65,18 @@ func NewGame(config *model.Config, settings *model.Settings, conns []model.Conne
 		LastTalkIdxMap:    make(map[*model.Agent]int),
 		LastWhisperIdxMap: make(map[*model.Agent]int),
 		IsFinished:        false,
+		IsStepMode:        config.Game.Debug.StepMode,
+		TalkValidator:     util.NewTalkValidator(*config),
+		pauseCond:         sync.NewCond(&sync.Mutex{}),
 	}
 }
 
 func NewGameWithRole(config *model.Config, settings *model.Settings, roleMapConns map[model.Role][]model.Connection) *Game {
 	id := ulid.Make().String()
 	agents := util.CreateAgentsWithRole(roleMapConns)
+	if config.Game.Persona.Enable {
+		util.AssignPersonas(agents, config.Game.Persona.Pool)
+	}
 	gameStatus := model.NewInitializeGameStatus(agents)
 	gameStatuses := make(map[int]*model.GameStatus)
 	gameStatuses[0] = &gameStatus
@@ -61,67 +91,229 @@ func NewGameWithRole(config *model.Config, settings *model.Settings, roleMapConn
 		LastTalkIdxMap:    make(map[*model.Agent]int),
 		LastWhisperIdxMap: make(map[*model.Agent]int),
 		IsFinished:        false,
+		IsStepMode:        config.Game.Debug.StepMode,
+		TalkValidator:     util.NewTalkValidator(*config),
+		pauseCond:         sync.NewCond(&sync.Mutex{}),
 	}
 }
 
-func (g *Game) SetAnalysisService(analysisService *service.AnalysisService) {
-	g.AnalysisService = analysisService
+func (g *Game) SetEventBus(eventBus *service.EventBus) {
+	g.EventBus = eventBus
 }
 
-func (g *Game) SetDeprecatedLogService(deprecatedLogService *service.DeprecatedLogService) {
-	g.DeprecatedLogService = deprecatedLogService
+func (g *Game) Abort() {
+	g.pauseCond.L.Lock()
+	defer g.pauseCond.L.Unlock()
+	g.IsAborted = true
+	g.IsPaused = false
+	g.pauseCond.Broadcast()
+	// 送信済みのリクエストのレスポンスを待たずに終了できるように、待機中のリクエストを取り消す
+	for _, agent := range g.Agents {
+		if agent.Receiver != nil {
+			agent.Receiver.Cancel()
+		}
+	}
+	slog.Warn("ゲームの中断が要求されました", "id", g.ID)
 }
 
-func (g *Game) Start() model.Team {
-	slog.Info("ゲームを開始します", "id", g.ID)
-	if g.AnalysisService != nil {
-		g.AnalysisService.TrackStartGame(g.ID, g.Agents)
+func (g *Game) Pause() {
+	g.pauseCond.L.Lock()
+	defer g.pauseCond.L.Unlock()
+	g.IsPaused = true
+	slog.Info("ゲームを一時停止しました", "id", g.ID)
+}
+
+func (g *Game) Resume() {
+	g.pauseCond.L.Lock()
+	defer g.pauseCond.L.Unlock()
+	g.IsPaused = false
+	g.steps = 0
+	g.pauseCond.Broadcast()
+	slog.Info("ゲームを再開しました", "id", g.ID)
+}
+
+func (g *Game) SetStepMode(enable bool) {
+	g.pauseCond.L.Lock()
+	defer g.pauseCond.L.Unlock()
+	g.IsStepMode = enable
+	slog.Info("ステップ実行モードを設定しました", "id", g.ID, "enable", enable)
+}
+
+func (g *Game) Step(count int) {
+	g.pauseCond.L.Lock()
+	defer g.pauseCond.L.Unlock()
+	g.steps += count
+	g.pauseCond.Broadcast()
+	slog.Info("ゲームをステップ実行します", "id", g.ID, "count", count)
+}
+
+func (g *Game) Snapshot() map[string]interface{} {
+	g.pauseCond.L.Lock()
+	defer g.pauseCond.L.Unlock()
+	snapshot := map[string]interface{}{
+		"game_id":      g.ID,
+		"day":          g.CurrentDay,
+		"phase":        g.CurrentPhase,
+		"is_finished":  g.IsFinished,
+		"is_aborted":   g.IsAborted,
+		"is_paused":    g.IsPaused,
+		"is_step_mode": g.IsStepMode,
+		"remain_steps": g.steps,
+	}
+	if g.statusSnapshot != nil {
+		snapshot["game_status"] = g.statusSnapshot
+	}
+	return snapshot
+}
+
+func (g *Game) Finished() bool {
+	g.pauseCond.L.Lock()
+	defer g.pauseCond.L.Unlock()
+	return g.IsFinished
+}
+
+func (g *Game) Aborted() bool {
+	g.pauseCond.L.Lock()
+	defer g.pauseCond.L.Unlock()
+	return g.IsAborted
+}
+
+// ゲームの状態はゲームのゴルーチンのみが更新するため、その時点の状態をゲームのゴルーチンで記録する
+// pauseCond.L を保持した状態で呼び出す
+func (g *Game) captureStatus() {
+	if data, err := json.Marshal(g.GameStatuses[g.CurrentDay]); err == nil {
+		g.statusSnapshot = data
+	}
+}
+
+func (g *Game) enterPhase(phase string) {
+	g.pauseCond.L.Lock()
+	defer g.pauseCond.L.Unlock()
+	g.CurrentPhase = phase
+	if g.IsStepMode && !g.IsAborted {
+		g.IsPaused = true
+		g.steps = 0
+		slog.Info("フェーズの開始時にゲームを一時停止しました", "id", g.ID, "day", g.CurrentDay, "phase", phase)
+	}
+}
+
+func (g *Game) waitIfPaused(consume bool) bool {
+	g.pauseCond.L.Lock()
+	defer g.pauseCond.L.Unlock()
+	// 一時停止中のみ、ゲームの状態をスナップショットに含める
+	for g.IsPaused && g.steps == 0 {
+		if g.statusSnapshot == nil {
+			g.captureStatus()
+		}
+		g.pauseCond.Wait()
+	}
+	g.statusSnapshot = nil
+	if g.IsPaused && consume {
+		g.steps--
+	}
+	return g.IsAborted
+}
+
+func (g *Game) header() model.EventHeader {
+	return model.EventHeader{
+		GameID:    g.ID,
+		Day:       g.CurrentDay,
+		Timestamp: time.Now(),
+	}
+}
+
+func (g *Game) publish(event model.Event) {
+	if g.EventBus != nil {
+		g.EventBus.Publish(event)
 	}
-	if g.DeprecatedLogService != nil {
-		g.DeprecatedLogService.TrackStartGame(g.ID, g.Agents)
+}
+
+func (g *Game) agentValues() []model.Agent {
+	agents := make([]model.Agent, 0, len(g.Agents))
+	for _, agent := range g.Agents {
+		agents = append(agents, *agent)
 	}
+	return agents
+}
+
+func (g *Game) Start() model.Team {
+	slog.Info("ゲームを開始します", "id", g.ID)
+	g.publish(model.GameStartedEvent{EventHeader: g.header(), Agents: g.agentValues()})
 	g.requestToEveryone(model.R_INITIALIZE)
 	var winSide model.Team = model.T_NONE
-	for winSide == model.T_NONE && util.CalcHasErrorAgents(g.Agents) < int(float64(len(g.Agents))*g.Config.Game.MaxContinueErrorRatio) {
-		g.progressDay()
-		g.progressNight()
+	for winSide == model.T_NONE && !g.waitIfPaused(false) && util.CalcHasErrorAgents(g.Agents) < int(float64(len(g.Agents))*g.Config.Game.MaxContinueErrorRatio) {
+		g.progressPhases()
 		gameStatus := g.GameStatuses[g.CurrentDay].NextDay()
+		g.pauseCond.L.Lock()
 		g.GameStatuses[g.CurrentDay+1] = &gameStatus
 		g.CurrentDay++
+		g.pauseCond.L.Unlock()
 		slog.Info("日付が進みました", "id", g.ID, "day", g.CurrentDay)
 		winSide = util.CalcWinSideTeam(gameStatus.StatusMap)
 	}
 	if winSide == model.T_NONE {
-		slog.Warn("エラーが多発したため、ゲームを終了します", "id", g.ID)
+		if g.Aborted() {
+			slog.Warn("ゲームが中断されたため、ゲームを終了します", "id", g.ID)
+		} else {
+			slog.Warn("エラーが多発したため、ゲームを終了します", "id", g.ID)
+		}
 	}
 	g.requestToEveryone(model.R_FINISH)
-	if g.DeprecatedLogService != nil {
-		for _, agent := range g.Agents {
-			g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,status,%d,%s,%s,%s", g.CurrentDay, agent.Idx, agent.Role.Name, g.GameStatuses[g.CurrentDay].StatusMap[*agent].String(), agent.Name))
-		}
-		villagers, werewolves := util.CountAliveTeams(g.GameStatuses[g.CurrentDay].StatusMap)
-		g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,result,%d,%d,%s", g.CurrentDay, villagers, werewolves, winSide))
+	villagers, werewolves := util.CountAliveTeams(g.GameStatuses[g.CurrentDay].StatusMap)
+	endedEvent := model.GameEndedEvent{
+		EventHeader: g.header(),
+		WinSide:     winSide,
+		Agents:      g.agentValues(),
+		StatusMap:   g.GameStatuses[g.CurrentDay].StatusMap,
+		Villagers:   villagers,
+		Werewolves:  werewolves,
 	}
 	g.closeAllAgents()
-	if g.AnalysisService != nil {
-		g.AnalysisService.TrackEndGame(g.ID, winSide)
-	}
-	if g.DeprecatedLogService != nil {
-		g.DeprecatedLogService.TrackEndGame(g.ID)
-	}
+	g.removeCheckpoint()
+	g.publish(endedEvent)
 	slog.Info("ゲームが終了しました", "id", g.ID, "winSide", winSide)
+	g.pauseCond.L.Lock()
 	g.IsFinished = true
+	g.captureStatus()
+	g.pauseCond.L.Unlock()
 	return winSide
 }
 
+// 各フェーズの開始前にチェックポイントを保存し、チェックポイントから再開した日は保存したフェーズから進行する
+func (g *Game) progressPhases() {
+	phases := []dayPhase{
+		{name: "day", firstDay: true, run: g.progressDay},
+		{name: "night", firstDay: true, run: g.progressNight},
+		{name: "execution", run: g.doExecution},
+		{name: "divine", firstDay: true, run: g.doDivine},
+		{name: "whisper", run: g.doWhisper},
+		{name: "guard", run: g.doGuard},
+		{name: "attack", run: g.doAttack},
+	}
+	for _, phase := range phases {
+		if g.resumePhase != "" {
+			if phase.name != g.resumePhase {
+				continue
+			}
+			g.resumePhase = ""
+		}
+		if g.CurrentDay == 0 && !phase.firstDay {
+			continue
+		}
+		g.saveCheckpoint(phase.name)
+		phase.run()
+	}
+	slog.Info("夜を終了します", "id", g.ID, "day", g.CurrentDay)
+}
+
 func (g *Game) progressDay() {
 	slog.Info("昼を開始します", "id", g.ID, "day", g.CurrentDay)
 	g.requestToEveryone(model.R_DAILY_INITIALIZE)
-	if g.DeprecatedLogService != nil {
-		for _, agent := range g.Agents {
-			g.DeprecatedLogService.AppendLog(g.ID, fmt.Sprintf("%d,status,%d,%s,%s,%s", g.CurrentDay, agent.Idx, agent.Role.Name, g.GameStatuses[g.CurrentDay].StatusMap[*agent].String(), agent.Name))
-		}
-	}
+	g.publish(model.DayStartedEvent{
+		EventHeader: g.header(),
+		Agents:      g.agentValues(),
+		StatusMap:   g.GameStatuses[g.CurrentDay].StatusMap,
+	})
 	if g.Settings.IsTalkOnFirstDay && g.CurrentDay == 0 {
 		g.doWhisper()
 	}
@@ -135,14 +327,4 @@ func (g *Game) progressNight() {
 	if g.Settings.IsTalkOnFirstDay && g.CurrentDay == 0 {
 		g.doWhisper()
 	}
-	if g.CurrentDay != 0 {
-		g.doExecution()
-	}
-	g.doDivine()
-	if g.CurrentDay != 0 {
-		g.doWhisper()
-		g.doGuard()
-		g.doAttack()
-	}
-	slog.Info("夜を終了します", "id", g.ID, "day", g.CurrentDay)
 }
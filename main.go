@@ -3,9 +3,6 @@ package main
 import (
 	"flag"
 	"os"
-
-	"github.com/kano-lab/aiwolf-nlp-server/core"
-	"github.com/kano-lab/aiwolf-nlp-server/model"
 )
 
 var (
@@ -14,22 +11,37 @@ var (
 	build    string
 )
 
+// 従来のフラグはサブコマンドのエイリアスとして扱う
+var (
+	configPath    = flag.String("c", defaultConfigPath, "設定ファイルのパス")
+	analyzerMode  = flag.Bool("a", false, "解析モード (analyze と同じ)")
+	reductionMode = flag.Bool("r", false, "縮約モード (reduce と同じ)")
+	srcConfigPath = flag.String("s", "", "ソース設定ファイルのパス")
+	dstConfigPath = flag.String("d", "", "デスティネーション設定ファイルのパス")
+	showVersion   = flag.Bool("v", false, "バージョンを表示")
+	showHelp      = flag.Bool("h", false, "ヘルプを表示")
+	sets          setFlags
+)
+
+func init() {
+	flag.Var(&sets, "set", "設定値の上書き (key.path=value の形式、複数指定可)")
+}
+
 func main() {
-	var (
-		configPath    = flag.String("c", "./default.yml", "設定ファイルのパス")
-		analyzerMode  = flag.Bool("a", false, "解析モード")
-		reductionMode = flag.Bool("r", false, "縮約モード")
-		srcConfigPath = flag.String("s", "", "ソース設定ファイルのパス")
-		dstConfigPath = flag.String("d", "", "デスティネーション設定ファイルのパス")
-		showVersion   = flag.Bool("v", false, "バージョンを表示")
-		showHelp      = flag.Bool("h", false, "ヘルプを表示")
-	)
+	if len(os.Args) > 1 {
+		if cmd := findCommand(os.Args[1]); cmd != nil {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+	}
+
+	flag.Usage = usage
 	flag.Parse()
 
 	if *showVersion {
-		println("version:", version)
-		println("revision:", revision)
-		println("build:", build)
+		printVersion()
 		os.Exit(0)
 	}
 
@@ -38,29 +50,24 @@ func main() {
 		os.Exit(0)
 	}
 
-	config, err := model.LoadFromPath(*configPath)
-	if err != nil {
-		panic(err)
-	}
-
-	if *analyzerMode {
-		core.Analyzer(*config)
-		return
-	}
-
-	if *reductionMode {
-		srcConfig, err := model.LoadFromPath(*srcConfigPath)
-		if err != nil {
-			panic(err)
+	var err error
+	switch {
+	case *analyzerMode:
+		args := []string{"-c", *configPath}
+		for _, set := range sets {
+			args = append(args, "-set", set)
 		}
-		dstConfig, err := model.LoadFromPath(*dstConfigPath)
-		if err != nil {
-			panic(err)
+		err = runAnalyze(args)
+	case *reductionMode:
+		err = runReduce([]string{"-s", *srcConfigPath, "-d", *dstConfigPath})
+	default:
+		args := []string{"-c", *configPath}
+		for _, set := range sets {
+			args = append(args, "-set", set)
 		}
-		core.Reduction(*srcConfig, *dstConfig)
-		return
+		err = runServe(args)
+	}
+	if err != nil {
+		exitWithError(err)
 	}
-
-	server := core.NewServer(*config)
-	server.Run()
 }
@@ -11,12 +11,22 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+var (
+	ErrResponseTimeout     = errors.New("リクエストのレスポンス受信がタイムアウトしました")
+	ErrInvalidNameResponse = errors.New("不正なNAMEリクエストのレスポンスを受信しました")
+	ErrNameResponseTimeout = errors.New("NAMEリクエストのレスポンス受信がタイムアウトしました")
+	ErrRequestCanceled     = errors.New("ゲームが中断されたため、リクエストを取り消しました")
+)
+
 type Agent struct {
 	Idx        int
 	Team       string
 	Name       string
 	Role       Role
+	Persona    *Persona
 	Connection *websocket.Conn
+	Receiver   *Receiver
+	IsHuman    bool
 	HasError   bool
 }
 
@@ -27,6 +37,8 @@ func NewAgent(idx int, role Role, conn Connection) (*Agent, error) {
 		Name:       conn.Name,
 		Role:       role,
 		Connection: conn.Conn,
+		Receiver:   conn.Receiver,
+		IsHuman:    conn.IsHuman,
 		HasError:   false,
 	}
 	slog.Info("エージェントを作成しました", "idx", agent.Idx, "agent", agent.String(), "role", agent.Role, "connection", agent.Connection.RemoteAddr())
@@ -38,6 +50,17 @@ func (a *Agent) SendPacket(packet Packet, actionTimeout, responseTimeout, accept
 		slog.Error("エージェントにエラーが発生しているため、リクエストを送信できません", "agent", a.String())
 		return "", errors.New("エージェントにエラーが発生しているため、リクエストを送信できません")
 	}
+	if !a.Receiver.IsAlive() {
+		slog.Error("接続が切断されているため、リクエストを送信できません", "agent", a.String(), "error", a.Receiver.Err())
+		a.HasError = true
+		return "", errors.New("接続が切断されているため、リクエストを送信できません")
+	}
+	for _, message := range a.Receiver.DiscardStale() {
+		slog.Warn("プロトコルエラー: 対応するリクエストのないレスポンスを破棄しました", "agent", a.String(), "response", message)
+	}
+	if packet.RequestID == 0 {
+		packet.RequestID = a.Receiver.NextRequestID()
+	}
 	req, err := json.Marshal(packet)
 	if err != nil {
 		slog.Error("パケットの作成に失敗しました", "error", err)
@@ -52,31 +75,37 @@ func (a *Agent) SendPacket(packet Packet, actionTimeout, responseTimeout, accept
 	}
 	slog.Info("パケットを送信しました", "agent", a.String(), "packet", packet)
 	if packet.Request.RequireResponse {
-		responseChan := make(chan []byte)
-		errChan := make(chan error)
-		go func() {
-			_, res, err := a.Connection.ReadMessage()
-			if err != nil {
-				errChan <- err
-				return
+		timeout := time.After(actionTimeout + acceptableTimeout)
+	wait:
+		for {
+			select {
+			case res := <-a.Receiver.Messages():
+				text, requestID, echoed := parseResponse(res)
+				if echoed && requestID != packet.RequestID {
+					slog.Warn("プロトコルエラー: リクエストIDが一致しないレスポンスを破棄しました", "agent", a.String(), "request_id", packet.RequestID, "response_request_id", requestID)
+					continue
+				}
+				slog.Info("レスポンスを受信しました", "agent", a.String(), "response", string(res))
+				return text, nil
+			case <-a.Receiver.Done():
+				err := a.Receiver.Err()
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					slog.Error("接続が閉じられました", "error", err)
+					a.HasError = true
+					return "", err
+				}
+				slog.Warn("レスポンスの受信に失敗したため、NAMEリクエストを送信します", "agent", a.String(), "error", err)
+				break wait
+			case <-timeout:
+				slog.Warn("レスポンスの受信がタイムアウトしたため、NAMEリクエストを送信します", "agent", a.String())
+				break wait
+			case <-a.Receiver.Canceled():
+				slog.Warn("ゲームが中断されたため、リクエストを取り消しました", "agent", a.String())
+				return "", ErrRequestCanceled
 			}
-			responseChan <- res
-		}()
-		select {
-		case res := <-responseChan:
-			slog.Info("レスポンスを受信しました", "agent", a.String(), "response", string(res))
-			return strings.TrimRight(string(res), "\n"), nil
-		case err := <-errChan:
-			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
-				slog.Error("接続が閉じられました", "error", err)
-				a.HasError = true
-				return "", err
-			}
-			slog.Warn("レスポンスの受信に失敗したため、NAMEリクエストを送信します", "agent", a.String(), "error", err)
-		case <-time.After(actionTimeout + time.Second*5):
-			slog.Warn("レスポンスの受信がタイムアウトしたため、NAMEリクエストを送信します", "agent", a.String())
 		}
-		nameReq, err := json.Marshal(Packet{Request: &R_NAME})
+		nameRequestID := a.Receiver.NextRequestID()
+		nameReq, err := json.Marshal(Packet{GameID: packet.GameID, RequestID: nameRequestID, Request: &R_NAME})
 		if err != nil {
 			slog.Error("NAMEパケットの作成に失敗しました", "error", err)
 			a.HasError = true
@@ -89,35 +118,72 @@ func (a *Agent) SendPacket(packet Packet, actionTimeout, responseTimeout, accept
 			return "", err
 		}
 		slog.Info("NAMEパケットを送信しました", "agent", a.String())
-		select {
-		case res := <-responseChan:
-			if strings.TrimRight(string(res), "\n") == a.Name {
-				slog.Info("NAMEリクエストのレスポンスを受信しました", "agent", a.String(), "response", string(res))
-				return "", errors.New("リクエストのレスポンス受信がタイムアウトしました")
-			} else {
+		// タイムアウトしたリクエストへの遅れたレスポンスは、NAMEリクエストのレスポンスより先に1つだけ受け付けて破棄する
+		late := false
+		deadline := time.After(responseTimeout)
+		for {
+			select {
+			case res := <-a.Receiver.Messages():
+				text, requestID, echoed := parseResponse(res)
+				if echoed && requestID != nameRequestID {
+					slog.Warn("プロトコルエラー: リクエストIDが一致しないレスポンスを破棄しました", "agent", a.String(), "request_id", nameRequestID, "response_request_id", requestID)
+					continue
+				}
+				if text == a.Name {
+					slog.Info("NAMEリクエストのレスポンスを受信しました", "agent", a.String(), "response", string(res))
+					return "", ErrResponseTimeout
+				}
+				if !echoed && !late {
+					late = true
+					slog.Warn("プロトコルエラー: タイムアウトしたリクエストへの遅れたレスポンスを破棄しました", "agent", a.String(), "request_id", packet.RequestID, "response", string(res))
+					continue
+				}
 				slog.Error("不正なNAMEリクエストのレスポンスを受信しました", "agent", a.String(), "response", string(res))
 				a.HasError = true
-				return "", errors.New("不正なNAMEリクエストのレスポンスを受信しました")
+				return "", ErrInvalidNameResponse
+			case <-a.Receiver.Done():
+				err := a.Receiver.Err()
+				slog.Error("NAMEリクエストのレスポンス受信に失敗しました", "agent", a.String(), "error", err)
+				a.HasError = true
+				return "", err
+			case <-deadline:
+				slog.Error("NAMEリクエストのレスポンス受信がタイムアウトしました", "agent", a.String())
+				a.HasError = true
+				return "", ErrNameResponseTimeout
+			case <-a.Receiver.Canceled():
+				slog.Warn("ゲームが中断されたため、リクエストを取り消しました", "agent", a.String())
+				return "", ErrRequestCanceled
 			}
-		case err := <-errChan:
-			slog.Error("NAMEリクエストのレスポンス受信に失敗しました", "agent", a.String(), "error", err)
-			a.HasError = true
-			return "", err
-		case <-time.After(responseTimeout):
-			slog.Error("NAMEリクエストのレスポンス受信がタイムアウトしました", "agent", a.String())
-			a.HasError = true
-			return "", errors.New("NAMEリクエストのレスポンス受信がタイムアウトしました")
 		}
 	}
 	return "", nil
 }
 
+// requestId を含むJSON形式のレスポンスは、リクエストIDで対応付ける
+func parseResponse(message []byte) (string, int, bool) {
+	text := strings.TrimRight(string(message), "\n")
+	if !strings.HasPrefix(text, "{") {
+		return text, 0, false
+	}
+	var echoed struct {
+		RequestID *int    `json:"requestId"`
+		Response  *string `json:"response"`
+	}
+	if err := json.Unmarshal([]byte(text), &echoed); err != nil || echoed.RequestID == nil || echoed.Response == nil {
+		return text, 0, false
+	}
+	return strings.TrimRight(*echoed.Response, "\n"), *echoed.RequestID, true
+}
+
 func (a *Agent) Close() {
 	a.Connection.Close()
 	slog.Info("エージェントをクローズしました", "agent", a.String())
 }
 
 func (a Agent) String() string {
+	if a.Persona != nil {
+		return a.Persona.Name
+	}
 	return "Agent[" + fmt.Sprintf("%02d", a.Idx) + "]"
 }
 
@@ -1,8 +1,14 @@
 package model
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -14,10 +20,22 @@ type Config struct {
 			Host string `yaml:"host"`
 			Port int    `yaml:"port"`
 		} `yaml:"web_socket"`
-		SelfMatch bool `yaml:"self_match"`
+		SelfMatch bool   `yaml:"self_match"`
+		Language  string `yaml:"language"`
+		Heartbeat struct {
+			Enable   bool          `yaml:"enable"`
+			Interval time.Duration `yaml:"interval"`
+			Timeout  time.Duration `yaml:"timeout"`
+		} `yaml:"heartbeat"`
+		Shutdown struct {
+			Timeout          time.Duration `yaml:"timeout"`
+			ProgressInterval time.Duration `yaml:"progress_interval"`
+		} `yaml:"shutdown"`
 	} `yaml:"server"`
 	Game struct {
 		AgentCount            int     `yaml:"agent_count"`
+		Language              string  `yaml:"language"`
+		LanguageDetection     bool    `yaml:"language_detection"`
 		VoteVisibility        bool    `yaml:"vote_visibility"`
 		TalkOnFirstDay        bool    `yaml:"talk_on_first_day"`
 		MaxContinueErrorRatio float64 `yaml:"max_continue_error_ratio"`
@@ -37,32 +55,83 @@ type Config struct {
 			MaxCount int `yaml:"max_count"`
 		} `yaml:"skip"`
 		Vote struct {
-			MaxCount int `yaml:"max_count"`
+			MaxCount        int    `yaml:"max_count"`
+			Mode            string `yaml:"mode"`
+			Declaration     bool   `yaml:"declaration"`
+			Runoff          bool   `yaml:"runoff"`
+			TieBreak        string `yaml:"tie_break"`
+			TimeoutFallback string `yaml:"timeout_fallback"`
 		} `yaml:"vote"`
 		Attack struct {
-			MaxCount      int  `yaml:"max_count"`
-			AllowNoTarget bool `yaml:"allow_no_target"`
+			MaxCount      int    `yaml:"max_count"`
+			AllowNoTarget bool   `yaml:"allow_no_target"`
+			TieBreak      string `yaml:"tie_break"`
 		} `yaml:"attack"`
+		Fallback       map[string]string `yaml:"fallback"`
+		TalkValidation struct {
+			Enable                 bool     `yaml:"enable"`
+			MaxCharacters          int      `yaml:"max_characters"`
+			MaxBytes               int      `yaml:"max_bytes"`
+			Normalize              bool     `yaml:"normalize"`
+			StripControlCharacters bool     `yaml:"strip_control_characters"`
+			BannedPatterns         []string `yaml:"banned_patterns"`
+			Action                 string   `yaml:"action"`
+		} `yaml:"talk_validation"`
+		Persona struct {
+			Enable bool      `yaml:"enable"`
+			Pool   []Persona `yaml:"pool"`
+		} `yaml:"persona"`
+		Debug struct {
+			StepMode bool `yaml:"step_mode"`
+		} `yaml:"debug"`
 		Timeout struct {
-			Action     time.Duration `yaml:"action"`
-			Response   time.Duration `yaml:"response"`
-			Acceptable time.Duration `yaml:"acceptable"`
+			Action     time.Duration            `yaml:"action"`
+			Response   time.Duration            `yaml:"response"`
+			Acceptable time.Duration            `yaml:"acceptable"`
+			PerRequest map[string]time.Duration `yaml:"per_request"`
 		} `yaml:"timeout"`
 	} `yaml:"game"`
+	HumanClient struct {
+		Enable  bool     `yaml:"enable"`
+		Path    string   `yaml:"path"`
+		Teams   []string `yaml:"teams"`
+		Timeout struct {
+			Action   time.Duration `yaml:"action"`
+			Response time.Duration `yaml:"response"`
+		} `yaml:"timeout"`
+	} `yaml:"human_client"`
 	AnalysisService struct {
 		Enable    bool   `yaml:"enable"`
 		OutputDir string `yaml:"output_dir"`
 		Filename  string `yaml:"filename"`
+		Retention struct {
+			MaxGames int           `yaml:"max_games"`
+			MaxAge   time.Duration `yaml:"max_age"`
+		} `yaml:"retention"`
 	} `yaml:"analysis_service"`
 	ApiService struct {
-		Enable             bool `yaml:"enable"`
-		PublishRunningGame bool `yaml:"publish_running_game"`
+		Enable             bool   `yaml:"enable"`
+		PublishRunningGame bool   `yaml:"publish_running_game"`
+		RevealToken        string `yaml:"reveal_token"`
+		Viewer             struct {
+			Enable bool   `yaml:"enable"`
+			Path   string `yaml:"path"`
+		} `yaml:"viewer"`
 	} `yaml:"api_service"`
 	DeprecatedLogService struct {
 		Enable    bool   `yaml:"enable"`
 		OutputDir string `yaml:"output_dir"`
 		Filename  string `yaml:"filename"`
 	} `yaml:"deprecated_log_service"`
+	AdminService struct {
+		Enable bool   `yaml:"enable"`
+		Token  string `yaml:"token"`
+	} `yaml:"admin_service"`
+	MetricsService struct {
+		Enable  bool      `yaml:"enable"`
+		Path    string    `yaml:"path"`
+		Buckets []float64 `yaml:"buckets"`
+	} `yaml:"metrics_service"`
 	MatchOptimizer struct {
 		Enable       bool   `yaml:"enable"`
 		TeamCount    int    `yaml:"team_count"`
@@ -70,20 +139,220 @@ type Config struct {
 		OutputPath   string `yaml:"output_path"`
 		InfiniteLoop bool   `yaml:"infinite_loop"`
 	} `yaml:"match_optimizer"`
+	Checkpoint struct {
+		Enable        bool          `yaml:"enable"`
+		OutputDir     string        `yaml:"output_dir"`
+		ResumeTimeout time.Duration `yaml:"resume_timeout"`
+	} `yaml:"checkpoint"`
+	Analyzer struct {
+		OutputDir string `yaml:"output_dir"`
+		Html      bool   `yaml:"html"`
+	} `yaml:"analyzer"`
+	resolved yaml.MapSlice
+}
+
+type ConfigError struct {
+	Field   string
+	Message string
 }
 
-const WebSocketExternalHost = "0.0.0.0"
+func (e ConfigError) Error() string {
+	return e.Field + ": " + e.Message
+}
 
-func LoadFromPath(path string) (*Config, error) {
+func LoadFromPath(path string, overrides ...Override) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		slog.Error("設定ファイルの読み込みに失敗しました", "error", err)
 		return nil, err
 	}
-	var config Config
+	var node yaml.MapSlice
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		slog.Error("設定ファイルのパースに失敗しました", "error", err)
+		return nil, err
+	}
+	for _, override := range overrides {
+		node = override.apply(node)
+		slog.Info("設定を上書きしました", "key", override.Key, "source", override.Source)
+	}
+	data, err = yaml.Marshal(node)
+	if err != nil {
+		slog.Error("設定ファイルのパースに失敗しました", "error", err)
+		return nil, err
+	}
+	config := defaultConfig()
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		slog.Error("設定ファイルのパースに失敗しました", "error", err)
 		return nil, err
 	}
+	config.resolved = node
+	errs := unknownKeys(node, reflect.TypeOf(config), "")
+	if err := config.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		err := errors.Join(errs...)
+		slog.Error("設定ファイルの検証に失敗しました", "error", err)
+		return nil, err
+	}
 	return &config, nil
 }
+
+// 後から追加されたキーが設定ファイルにない場合の既定値
+// キーが指定された場合は設定ファイルの値で上書きされ、Validate で検証される
+func defaultConfig() Config {
+	var config Config
+	config.Server.Language = string(L_JA)
+	config.Server.Shutdown.Timeout = 30 * time.Minute
+	config.Server.Shutdown.ProgressInterval = 15 * time.Second
+	config.Game.Language = string(L_JA)
+	return config
+}
+
+// yaml.UnmarshalStrict のエラーには親のキーが含まれないため、キーのパスを辿って未知のキーを検出する
+func unknownKeys(node interface{}, t reflect.Type, prefix string) []error {
+	errs := make([]error, 0)
+	switch t.Kind() {
+	case reflect.Struct:
+		mapSlice, ok := node.(yaml.MapSlice)
+		if !ok {
+			return errs
+		}
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			if key != "" && key != "-" {
+				fields[key] = t.Field(i).Type
+			}
+		}
+		for _, item := range mapSlice {
+			key := fmt.Sprint(item.Key)
+			field := strings.TrimPrefix(prefix+"."+key, ".")
+			fieldType, exists := fields[key]
+			if !exists {
+				errs = append(errs, ConfigError{Field: field, Message: "未知のキーです"})
+				continue
+			}
+			errs = append(errs, unknownKeys(item.Value, fieldType, field)...)
+		}
+	case reflect.Slice:
+		if items, ok := node.([]interface{}); ok {
+			for i, item := range items {
+				errs = append(errs, unknownKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i))...)
+			}
+		}
+	case reflect.Ptr:
+		errs = append(errs, unknownKeys(node, t.Elem(), prefix)...)
+	}
+	return errs
+}
+
+func (c Config) Validate() error {
+	errs := make([]error, 0)
+	check := func(ok bool, field string, message string) {
+		if !ok {
+			errs = append(errs, ConfigError{Field: field, Message: message})
+		}
+	}
+	checkPath := func(field string, path string) {
+		check(strings.HasPrefix(path, "/"), field, "/ から始まるパスを指定してください")
+	}
+
+	check(c.Server.WebSocket.Host != "", "server.web_socket.host", "ホスト名を指定してください")
+	check(c.Server.WebSocket.Port > 0 && c.Server.WebSocket.Port <= 65535, "server.web_socket.port", "1から65535の範囲で指定してください")
+	check(LanguageFromString(c.Server.Language) != "", "server.language", "ja もしくは en を指定してください")
+	if c.Server.Heartbeat.Enable {
+		check(c.Server.Heartbeat.Interval > 0, "server.heartbeat.interval", "0より大きい時間を指定してください")
+		check(c.Server.Heartbeat.Timeout > 0, "server.heartbeat.timeout", "0より大きい時間を指定してください")
+	}
+	check(c.Server.Shutdown.Timeout > 0, "server.shutdown.timeout", "0より大きい時間を指定してください")
+	check(c.Server.Shutdown.ProgressInterval > 0, "server.shutdown.progress_interval", "0より大きい時間を指定してください")
+
+	check(Roles(c.Game.AgentCount) != nil, "game.agent_count", "対応する役職の人数がありません")
+	check(LanguageFromString(c.Game.Language) != "", "game.language", "ja もしくは en を指定してください")
+	check(c.Game.MaxContinueErrorRatio >= 0 && c.Game.MaxContinueErrorRatio <= 1, "game.max_continue_error_ratio", "0から1の範囲で指定してください")
+	check(c.Game.Talk.MaxCount.PerAgent > 0, "game.talk.max_count.per_agent", "1以上を指定してください")
+	check(c.Game.Talk.MaxCount.PerDay > 0, "game.talk.max_count.per_day", "1以上を指定してください")
+	check(c.Game.Whisper.MaxCount.PerAgent > 0, "game.whisper.max_count.per_agent", "1以上を指定してください")
+	check(c.Game.Whisper.MaxCount.PerDay > 0, "game.whisper.max_count.per_day", "1以上を指定してください")
+	check(c.Game.Skip.MaxCount >= 0, "game.skip.max_count", "0以上を指定してください")
+	check(c.Game.Vote.MaxCount > 0, "game.vote.max_count", "1以上を指定してください")
+	check(c.Game.Vote.Mode == "" || VoteModeFromString(c.Game.Vote.Mode) != "", "game.vote.mode", "simultaneous もしくは sequential を指定してください")
+	check(c.Game.Vote.TieBreak == "" || TieBreakFromString(c.Game.Vote.TieBreak) != "", "game.vote.tie_break", "random, none, runoff, all のいずれかを指定してください")
+	check(c.Game.Vote.TimeoutFallback == "" || VoteFallbackFromString(c.Game.Vote.TimeoutFallback) != "", "game.vote.timeout_fallback", "abstain, random, exclude_self, last のいずれかを指定してください")
+	for key, fallback := range c.Game.Fallback {
+		check(slices.Contains(FallbackRequests, RequestFromString(strings.ToUpper(key))), "game.fallback."+key, "vote, declare_vote, attack, divine, guard のいずれかを指定してください")
+		check(FallbackFromString(fallback) != "", "game.fallback."+key, "abstain, random, exclude_self, last のいずれかを指定してください")
+	}
+	check(c.Game.Attack.MaxCount > 0, "game.attack.max_count", "1以上を指定してください")
+	check(c.Game.Attack.TieBreak == "" || TieBreakFromString(c.Game.Attack.TieBreak) != "", "game.attack.tie_break", "random, none, runoff, all のいずれかを指定してください")
+	if c.Game.TalkValidation.Enable {
+		check(c.Game.TalkValidation.MaxCharacters >= 0, "game.talk_validation.max_characters", "0以上を指定してください")
+		check(c.Game.TalkValidation.MaxBytes >= 0, "game.talk_validation.max_bytes", "0以上を指定してください")
+		check(c.Game.TalkValidation.Action == "" || ViolationActionFromString(c.Game.TalkValidation.Action) != "", "game.talk_validation.action", "truncate, skip, error のいずれかを指定してください")
+		for i, pattern := range c.Game.TalkValidation.BannedPatterns {
+			_, err := regexp.Compile(pattern)
+			check(err == nil, fmt.Sprintf("game.talk_validation.banned_patterns[%d]", i), "正規表現として不正です")
+		}
+	}
+	if c.Game.Persona.Enable {
+		check(len(c.Game.Persona.Pool) >= c.Game.AgentCount, "game.persona.pool", "エージェント数以上のペルソナを指定してください")
+		names := make(map[string]bool)
+		for i, persona := range c.Game.Persona.Pool {
+			check(persona.Name != "", fmt.Sprintf("game.persona.pool[%d].name", i), "表示名を指定してください")
+			check(!names[persona.Name], fmt.Sprintf("game.persona.pool[%d].name", i), "表示名が重複しています")
+			names[persona.Name] = true
+		}
+	}
+	check(c.Game.Timeout.Action > 0, "game.timeout.action", "0より大きい時間を指定してください")
+	check(c.Game.Timeout.Response > 0, "game.timeout.response", "0より大きい時間を指定してください")
+	check(c.Game.Timeout.Acceptable >= 0, "game.timeout.acceptable", "0以上の時間を指定してください")
+	for key, timeout := range c.Game.Timeout.PerRequest {
+		request := RequestFromString(strings.ToUpper(key))
+		check(request.RequireResponse && request != R_NAME, "game.timeout.per_request."+key, "レスポンスが必要なリクエストの種類を指定してください")
+		check(timeout > 0, "game.timeout.per_request."+key, "0より大きい時間を指定してください")
+	}
+
+	if c.HumanClient.Enable {
+		checkPath("human_client.path", c.HumanClient.Path)
+		check(len(c.HumanClient.Teams) > 0, "human_client.teams", "人間のプレイヤーとして参加できるチームを指定してください")
+		check(!slices.Contains(c.HumanClient.Teams, ""), "human_client.teams", "空のチーム名は指定できません")
+		check(c.HumanClient.Timeout.Action > 0, "human_client.timeout.action", "0より大きい時間を指定してください")
+		check(c.HumanClient.Timeout.Response > 0, "human_client.timeout.response", "0より大きい時間を指定してください")
+	}
+	if c.AnalysisService.Enable {
+		check(c.AnalysisService.OutputDir != "", "analysis_service.output_dir", "出力ディレクトリを指定してください")
+		check(c.AnalysisService.Filename != "", "analysis_service.filename", "ファイル名を指定してください")
+	}
+	check(c.AnalysisService.Retention.MaxGames >= 0, "analysis_service.retention.max_games", "0以上を指定してください")
+	check(c.AnalysisService.Retention.MaxAge >= 0, "analysis_service.retention.max_age", "0以上の時間を指定してください")
+	if c.ApiService.Enable {
+		check(c.AnalysisService.Enable, "api_service.enable", "analysis_service.enable を有効にしてください")
+		if c.ApiService.Viewer.Enable {
+			checkPath("api_service.viewer.path", c.ApiService.Viewer.Path)
+		}
+	}
+	if c.DeprecatedLogService.Enable {
+		check(c.DeprecatedLogService.OutputDir != "", "deprecated_log_service.output_dir", "出力ディレクトリを指定してください")
+		check(c.DeprecatedLogService.Filename != "", "deprecated_log_service.filename", "ファイル名を指定してください")
+	}
+	if c.AdminService.Enable {
+		check(c.AdminService.Token != "", "admin_service.token", "管理APIを有効にする場合はトークンを指定してください")
+	}
+	if c.MetricsService.Enable {
+		checkPath("metrics_service.path", c.MetricsService.Path)
+		for i, bucket := range c.MetricsService.Buckets {
+			check(bucket > 0 && (i == 0 || bucket > c.MetricsService.Buckets[i-1]), fmt.Sprintf("metrics_service.buckets[%d]", i), "0より大きい昇順の値を指定してください")
+		}
+	}
+	if c.MatchOptimizer.Enable {
+		check(c.MatchOptimizer.TeamCount >= c.Game.AgentCount, "match_optimizer.team_count", "game.agent_count 以上を指定してください")
+		check(c.MatchOptimizer.GameCount > 0, "match_optimizer.game_count", "1以上を指定してください")
+		check(c.MatchOptimizer.OutputPath != "", "match_optimizer.output_path", "出力ファイルを指定してください")
+	}
+	if c.Checkpoint.Enable {
+		check(c.Checkpoint.OutputDir != "", "checkpoint.output_dir", "出力ディレクトリを指定してください")
+		check(c.Checkpoint.ResumeTimeout > 0, "checkpoint.resume_timeout", "0より大きい時間を指定してください")
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const EnvPrefix = "AIWOLF_"
+
+type Override struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// key.path=value 形式の上書きをパースする
+func ParseOverride(s string, source string) (Override, error) {
+	key, value, found := strings.Cut(s, "=")
+	if !found || key == "" {
+		return Override{}, errors.New("上書きは key.path=value の形式で指定してください")
+	}
+	return Override{Key: strings.TrimSpace(key), Value: value, Source: source}, nil
+}
+
+// AIWOLF_SERVER_WEB_SOCKET_PORT のような環境変数を server.web_socket.port に対応付ける
+func EnvOverrides(environ []string) ([]Override, error) {
+	overrides := make([]Override, 0)
+	errs := make([]error, 0)
+	for _, env := range environ {
+		name, value, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		key, ok := envKeyPath(reflect.TypeOf(Config{}), strings.ToLower(strings.TrimPrefix(name, EnvPrefix)))
+		if !ok {
+			errs = append(errs, ConfigError{Field: name, Message: "対応する設定のキーがありません"})
+			continue
+		}
+		overrides = append(overrides, Override{Key: key, Value: value, Source: name})
+	}
+	return overrides, errors.Join(errs...)
+}
+
+func envKeyPath(t reflect.Type, name string) (string, bool) {
+	if t.Kind() != reflect.Struct {
+		return "", false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		if name == key {
+			return key, true
+		}
+		if rest, found := strings.CutPrefix(name, key+"_"); found {
+			if path, ok := envKeyPath(t.Field(i).Type, rest); ok {
+				return key + "." + path, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (o Override) apply(node yaml.MapSlice) yaml.MapSlice {
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(o.Value), &value); err != nil {
+		value = o.Value
+	}
+	return setPath(node, strings.Split(o.Key, "."), value)
+}
+
+func setPath(node yaml.MapSlice, keys []string, value interface{}) yaml.MapSlice {
+	for i, item := range node {
+		if fmt.Sprint(item.Key) != keys[0] {
+			continue
+		}
+		if len(keys) == 1 {
+			node[i].Value = value
+		} else {
+			child, _ := item.Value.(yaml.MapSlice)
+			node[i].Value = setPath(child, keys[1:], value)
+		}
+		return node
+	}
+	if len(keys) == 1 {
+		return append(node, yaml.MapItem{Key: keys[0], Value: value})
+	}
+	return append(node, yaml.MapItem{Key: keys[0], Value: setPath(nil, keys[1:], value)})
+}
+
+// 上書きを反映した設定をYAML形式で返す (管理APIと役職公開のトークンは伏せる)
+func (c Config) Dump() string {
+	node := c.resolved
+	if c.AdminService.Token != "" {
+		node = setPath(copyMapSlice(node), []string{"admin_service", "token"}, "********")
+	}
+	if c.ApiService.RevealToken != "" {
+		node = setPath(copyMapSlice(node), []string{"api_service", "reveal_token"}, "********")
+	}
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func copyMapSlice(node yaml.MapSlice) yaml.MapSlice {
+	copied := make(yaml.MapSlice, len(node))
+	for i, item := range node {
+		if child, ok := item.Value.(yaml.MapSlice); ok {
+			item.Value = copyMapSlice(child)
+		}
+		copied[i] = item
+	}
+	return copied
+}
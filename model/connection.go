@@ -9,9 +9,11 @@ import (
 )
 
 type Connection struct {
-	Team string
-	Name string
-	Conn *websocket.Conn
+	Team     string
+	Name     string
+	Conn     *websocket.Conn
+	IsHuman  bool
+	Receiver *Receiver
 }
 
 func NewConnection(conn *websocket.Conn) (*Connection, error) {
@@ -33,12 +35,13 @@ func NewConnection(conn *websocket.Conn) (*Connection, error) {
 		slog.Error("NAMEリクエストの受信に失敗しました", "error", err)
 		return nil, err
 	}
-	name := strings.TrimRight(string(res), "\n")
+	name, _, _ := parseResponse(res)
 	team := strings.TrimRight(name, "1234567890")
 	connection := Connection{
-		Team: team,
-		Name: name,
-		Conn: conn,
+		Team:     team,
+		Name:     name,
+		Conn:     conn,
+		Receiver: NewReceiver(conn),
 	}
 	slog.Info("クライアントが接続しました", "team", team, "name", name, "remote_addr", conn.RemoteAddr().String())
 	return &connection, nil
@@ -0,0 +1,171 @@
+package model
+
+import "time"
+
+type Event interface {
+	Name() string
+	Header() EventHeader
+}
+
+type EventHeader struct {
+	GameID    string    `json:"game_id"`
+	Day       int       `json:"day"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (h EventHeader) Header() EventHeader {
+	return h
+}
+
+type GameStartedEvent struct {
+	EventHeader
+	Agents []Agent `json:"agents"`
+}
+
+func (e GameStartedEvent) Name() string {
+	return "GAME_STARTED"
+}
+
+type DayStartedEvent struct {
+	EventHeader
+	Agents    []Agent          `json:"agents"`
+	StatusMap map[Agent]Status `json:"-"`
+}
+
+func (e DayStartedEvent) Name() string {
+	return "DAY_STARTED"
+}
+
+type TalkSpokenEvent struct {
+	EventHeader
+	Request  Request  `json:"request"`
+	Talk     Talk     `json:"talk"`
+	Decision Decision `json:"decision"`
+}
+
+func (e TalkSpokenEvent) Name() string {
+	return "TALK_SPOKEN"
+}
+
+type VoteCastEvent struct {
+	EventHeader
+	Request  Request  `json:"request"`
+	Vote     Vote     `json:"vote"`
+	Decision Decision `json:"decision"`
+}
+
+func (e VoteCastEvent) Name() string {
+	return "VOTE_CAST"
+}
+
+type ExecutedEvent struct {
+	EventHeader
+	Agent Agent `json:"agent"`
+}
+
+func (e ExecutedEvent) Name() string {
+	return "EXECUTED"
+}
+
+type DivinedEvent struct {
+	EventHeader
+	Judge    Judge    `json:"judge"`
+	Decision Decision `json:"decision"`
+}
+
+func (e DivinedEvent) Name() string {
+	return "DIVINED"
+}
+
+type GuardedEvent struct {
+	EventHeader
+	Guard    Guard    `json:"guard"`
+	Decision Decision `json:"decision"`
+}
+
+func (e GuardedEvent) Name() string {
+	return "GUARDED"
+}
+
+type AttackedEvent struct {
+	EventHeader
+	Agent     *Agent `json:"agent"`
+	Succeeded bool   `json:"succeeded"`
+}
+
+func (e AttackedEvent) Name() string {
+	return "ATTACKED"
+}
+
+type TieBrokenEvent struct {
+	EventHeader
+	Request    Request  `json:"request"`
+	TieBreak   TieBreak `json:"tie_break"`
+	Candidates []Agent  `json:"candidates"`
+	Resolved   []Agent  `json:"resolved"`
+}
+
+func (e TieBrokenEvent) Name() string {
+	return "TIE_BROKEN"
+}
+
+type TalkViolatedEvent struct {
+	EventHeader
+	Agent      Agent           `json:"agent"`
+	Request    Request         `json:"request"`
+	Violations []Violation     `json:"violations"`
+	Action     ViolationAction `json:"action"`
+	Text       string          `json:"text"`
+}
+
+func (e TalkViolatedEvent) Name() string {
+	return "TALK_VIOLATED"
+}
+
+type LanguageMismatchedEvent struct {
+	EventHeader
+	Request  Request  `json:"request"`
+	Talk     Talk     `json:"talk"`
+	Expected Language `json:"expected"`
+}
+
+func (e LanguageMismatchedEvent) Name() string {
+	return "LANGUAGE_MISMATCHED"
+}
+
+type RequestStartedEvent struct {
+	EventHeader
+	Agent  Agent  `json:"agent"`
+	Packet Packet `json:"packet"`
+}
+
+func (e RequestStartedEvent) Name() string {
+	return "REQUEST_STARTED"
+}
+
+type RequestEndedEvent struct {
+	EventHeader
+	Agent     Agent         `json:"agent"`
+	RequestID int           `json:"request_id"`
+	Request   Request       `json:"request"`
+	Response  string        `json:"response"`
+	Duration  time.Duration `json:"duration"`
+	Error     error         `json:"-"`
+}
+
+func (e RequestEndedEvent) Name() string {
+	return "REQUEST_ENDED"
+}
+
+type GameEndedEvent struct {
+	EventHeader
+	WinSide    Team             `json:"win_side"`
+	Agents     []Agent          `json:"agents"`
+	StatusMap  map[Agent]Status `json:"-"`
+	Villagers  int              `json:"villagers"`
+	Werewolves int              `json:"werewolves"`
+}
+
+func (e GameEndedEvent) Name() string {
+	return "GAME_ENDED"
+}
@@ -0,0 +1,34 @@
+package model
+
+type Fallback string
+
+const (
+	FB_ABSTAIN      Fallback = "abstain"
+	FB_RANDOM       Fallback = "random"
+	FB_EXCLUDE_SELF Fallback = "exclude_self"
+	FB_LAST         Fallback = "last"
+)
+
+func FallbackFromString(s string) Fallback {
+	switch s {
+	case "abstain":
+		return FB_ABSTAIN
+	case "random":
+		return FB_RANDOM
+	case "exclude_self":
+		return FB_EXCLUDE_SELF
+	case "last":
+		return FB_LAST
+	}
+	return ""
+}
+
+// 代わりの行動を設定できるリクエスト
+var FallbackRequests = []Request{R_VOTE, R_DECLARE_VOTE, R_ATTACK, R_DIVINE, R_GUARD}
+
+type Decision string
+
+const (
+	D_AGENT              Decision = "agent"
+	D_SERVER_SUBSTITUTED Decision = "server-substituted"
+)
@@ -1,19 +1,188 @@
 package model
 
+import (
+	"encoding/json"
+	"errors"
+)
+
 type GameStatus struct {
-	Day              int
-	MediumResult     *Judge
-	DivineResult     *Judge
-	ExecutedAgent    *Agent
-	AttackedAgent    *Agent
-	Guard            *Guard
-	Votes            []Vote
-	AttackVotes      []Vote
-	Talks            []Talk
-	Whispers         []Talk
-	StatusMap        map[Agent]Status
-	RemainTalkMap    map[Agent]int
-	RemainWhisperMap map[Agent]int
+	Day                  int              `json:"day"`
+	MediumResult         *Judge           `json:"mediumResult,omitempty"`
+	DivineResult         *Judge           `json:"divineResult,omitempty"`
+	ExecutedAgent        *Agent           `json:"executedAgent,omitempty"`
+	ExecutedAgents       []Agent          `json:"executedAgents,omitempty"`
+	AttackedAgent        *Agent           `json:"attackedAgent,omitempty"`
+	AttackedAgents       []Agent          `json:"attackedAgents,omitempty"`
+	Guard                *Guard           `json:"guard,omitempty"`
+	Votes                []Vote           `json:"votes"`
+	DeclaredVotes        []Vote           `json:"declaredVotes"`
+	VoteCandidates       []Agent          `json:"voteCandidates,omitempty"`
+	AttackVotes          []Vote           `json:"attackVotes"`
+	AttackVoteCandidates []Agent          `json:"attackVoteCandidates,omitempty"`
+	Talks                []Talk           `json:"talks"`
+	Whispers             []Talk           `json:"whispers"`
+	StatusMap            map[Agent]Status `json:"statusMap"`
+	RemainTalkMap        map[Agent]int    `json:"remainTalkMap"`
+	RemainWhisperMap     map[Agent]int    `json:"remainWhisperMap"`
+}
+
+func (g GameStatus) MarshalJSON() ([]byte, error) {
+	statusMap := make(map[string]Status)
+	for k, v := range g.StatusMap {
+		statusMap[k.String()] = v
+	}
+	remainTalkMap := make(map[string]int)
+	for k, v := range g.RemainTalkMap {
+		remainTalkMap[k.String()] = v
+	}
+	remainWhisperMap := make(map[string]int)
+	for k, v := range g.RemainWhisperMap {
+		remainWhisperMap[k.String()] = v
+	}
+	type Alias GameStatus
+	return json.Marshal(&struct {
+		*Alias
+		StatusMap        map[string]Status `json:"statusMap"`
+		RemainTalkMap    map[string]int    `json:"remainTalkMap"`
+		RemainWhisperMap map[string]int    `json:"remainWhisperMap"`
+	}{
+		Alias:            (*Alias)(&g),
+		StatusMap:        statusMap,
+		RemainTalkMap:    remainTalkMap,
+		RemainWhisperMap: remainWhisperMap,
+	})
+}
+
+// エージェントは表示名で出力されるため、ゲームのエージェントから復元する
+func UnmarshalGameStatus(data []byte, agents []*Agent) (*GameStatus, error) {
+	type rawJudge struct {
+		Day    int     `json:"day"`
+		Agent  string  `json:"agent"`
+		Target string  `json:"target"`
+		Result Species `json:"result"`
+	}
+	type rawVote struct {
+		Day    int    `json:"day"`
+		Agent  string `json:"agent"`
+		Target string `json:"target"`
+	}
+	type rawTalk struct {
+		Idx      int      `json:"idx"`
+		Day      int      `json:"day"`
+		Turn     int      `json:"turn"`
+		Agent    string   `json:"agent"`
+		Text     string   `json:"text"`
+		Language Language `json:"language"`
+	}
+	var raw struct {
+		Day                  int               `json:"day"`
+		MediumResult         *rawJudge         `json:"mediumResult"`
+		DivineResult         *rawJudge         `json:"divineResult"`
+		ExecutedAgent        *string           `json:"executedAgent"`
+		ExecutedAgents       []string          `json:"executedAgents"`
+		AttackedAgent        *string           `json:"attackedAgent"`
+		AttackedAgents       []string          `json:"attackedAgents"`
+		Guard                *rawVote          `json:"guard"`
+		Votes                []rawVote         `json:"votes"`
+		DeclaredVotes        []rawVote         `json:"declaredVotes"`
+		VoteCandidates       []string          `json:"voteCandidates"`
+		AttackVotes          []rawVote         `json:"attackVotes"`
+		AttackVoteCandidates []string          `json:"attackVoteCandidates"`
+		Talks                []rawTalk         `json:"talks"`
+		Whispers             []rawTalk         `json:"whispers"`
+		StatusMap            map[string]Status `json:"statusMap"`
+		RemainTalkMap        map[string]int    `json:"remainTalkMap"`
+		RemainWhisperMap     map[string]int    `json:"remainWhisperMap"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	agentMap := make(map[string]Agent)
+	for _, agent := range agents {
+		agentMap[agent.String()] = *agent
+	}
+	var unknown error
+	agent := func(name string) Agent {
+		a, exists := agentMap[name]
+		if !exists {
+			unknown = errors.New("ゲームの状態に不明なエージェントが含まれています")
+		}
+		return a
+	}
+	agentPtr := func(name *string) *Agent {
+		if name == nil {
+			return nil
+		}
+		a := agent(*name)
+		return &a
+	}
+	agentSlice := func(names []string) []Agent {
+		if names == nil {
+			return nil
+		}
+		slice := make([]Agent, len(names))
+		for i, name := range names {
+			slice[i] = agent(name)
+		}
+		return slice
+	}
+	judge := func(j *rawJudge) *Judge {
+		if j == nil {
+			return nil
+		}
+		return &Judge{Day: j.Day, Agent: agent(j.Agent), Target: agent(j.Target), Result: j.Result}
+	}
+	votes := func(raws []rawVote) []Vote {
+		slice := make([]Vote, len(raws))
+		for i, v := range raws {
+			slice[i] = Vote{Day: v.Day, Agent: agent(v.Agent), Target: agent(v.Target)}
+		}
+		return slice
+	}
+	talks := func(raws []rawTalk) []Talk {
+		slice := make([]Talk, len(raws))
+		for i, t := range raws {
+			slice[i] = Talk{Idx: t.Idx, Day: t.Day, Turn: t.Turn, Agent: agent(t.Agent), Text: t.Text, Language: t.Language}
+		}
+		return slice
+	}
+
+	status := GameStatus{
+		Day:                  raw.Day,
+		MediumResult:         judge(raw.MediumResult),
+		DivineResult:         judge(raw.DivineResult),
+		ExecutedAgent:        agentPtr(raw.ExecutedAgent),
+		ExecutedAgents:       agentSlice(raw.ExecutedAgents),
+		AttackedAgent:        agentPtr(raw.AttackedAgent),
+		AttackedAgents:       agentSlice(raw.AttackedAgents),
+		Votes:                votes(raw.Votes),
+		DeclaredVotes:        votes(raw.DeclaredVotes),
+		VoteCandidates:       agentSlice(raw.VoteCandidates),
+		AttackVotes:          votes(raw.AttackVotes),
+		AttackVoteCandidates: agentSlice(raw.AttackVoteCandidates),
+		Talks:                talks(raw.Talks),
+		Whispers:             talks(raw.Whispers),
+		StatusMap:            make(map[Agent]Status),
+		RemainTalkMap:        make(map[Agent]int),
+		RemainWhisperMap:     make(map[Agent]int),
+	}
+	if raw.Guard != nil {
+		status.Guard = &Guard{Day: raw.Guard.Day, Agent: agent(raw.Guard.Agent), Target: agent(raw.Guard.Target)}
+	}
+	for name, s := range raw.StatusMap {
+		status.StatusMap[agent(name)] = s
+	}
+	for name, count := range raw.RemainTalkMap {
+		status.RemainTalkMap[agent(name)] = count
+	}
+	for name, count := range raw.RemainWhisperMap {
+		status.RemainWhisperMap[agent(name)] = count
+	}
+	if unknown != nil {
+		return nil, unknown
+	}
+	return &status, nil
 }
 
 func NewInitializeGameStatus(agents []*Agent) GameStatus {
@@ -25,6 +194,7 @@ func NewInitializeGameStatus(agents []*Agent) GameStatus {
 		AttackedAgent:    nil,
 		Guard:            nil,
 		Votes:            []Vote{},
+		DeclaredVotes:    []Vote{},
 		AttackVotes:      []Vote{},
 		Talks:            []Talk{},
 		Whispers:         []Talk{},
@@ -47,6 +217,7 @@ func (g GameStatus) NextDay() GameStatus {
 		AttackedAgent:    nil,
 		Guard:            nil,
 		Votes:            []Vote{},
+		DeclaredVotes:    []Vote{},
 		AttackVotes:      []Vote{},
 		Talks:            []Talk{},
 		Whispers:         []Talk{},
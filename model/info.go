@@ -3,18 +3,25 @@ package model
 import "encoding/json"
 
 type Info struct {
-	Day            int              `json:"day"`
-	Agent          *Agent           `json:"agent,omitempty"`
-	MediumResult   *Judge           `json:"mediumResult,omitempty"`
-	DivineResult   *Judge           `json:"divineResult,omitempty"`
-	ExecutedAgent  *Agent           `json:"executedAgent,omitempty"`
-	AttackedAgent  *Agent           `json:"attackedAgent,omitempty"`
-	VoteList       []Vote           `json:"voteList,omitempty"`
-	AttackVoteList []Vote           `json:"attackVoteList,omitempty"`
-	TalkList       []Talk           `json:"-"`
-	WhisperList    []Talk           `json:"-"`
-	StatusMap      map[Agent]Status `json:"statusMap"`
-	RoleMap        map[Agent]Role   `json:"roleMap"`
+	Day                  int              `json:"day"`
+	Agent                *Agent           `json:"agent,omitempty"`
+	Persona              *Persona         `json:"persona,omitempty"`
+	MediumResult         *Judge           `json:"mediumResult,omitempty"`
+	DivineResult         *Judge           `json:"divineResult,omitempty"`
+	ExecutedAgent        *Agent           `json:"executedAgent,omitempty"`
+	ExecutedAgents       []Agent          `json:"executedAgents,omitempty"`
+	AttackedAgent        *Agent           `json:"attackedAgent,omitempty"`
+	AttackedAgents       []Agent          `json:"attackedAgents,omitempty"`
+	VoteList             []Vote           `json:"voteList,omitempty"`
+	CastVoteList         []Vote           `json:"castVoteList,omitempty"`
+	DeclaredVoteList     []Vote           `json:"declaredVoteList,omitempty"`
+	VoteCandidates       []Agent          `json:"voteCandidates,omitempty"`
+	AttackVoteList       []Vote           `json:"attackVoteList,omitempty"`
+	AttackVoteCandidates []Agent          `json:"attackVoteCandidates,omitempty"`
+	TalkList             []Talk           `json:"-"`
+	WhisperList          []Talk           `json:"-"`
+	StatusMap            map[Agent]Status `json:"statusMap"`
+	RoleMap              map[Agent]Role   `json:"roleMap"`
 }
 
 func (i Info) MarshalJSON() ([]byte, error) {
@@ -52,9 +59,11 @@ func NewInfo(agent *Agent, gameStatus *GameStatus, lastGameStatus *GameStatus, s
 		}
 		if lastGameStatus.ExecutedAgent != nil {
 			info.ExecutedAgent = lastGameStatus.ExecutedAgent
+			info.ExecutedAgents = lastGameStatus.ExecutedAgents
 		}
 		if lastGameStatus.AttackedAgent != nil {
 			info.AttackedAgent = lastGameStatus.AttackedAgent
+			info.AttackedAgents = lastGameStatus.AttackedAgents
 		}
 		if settings.IsVoteVisible {
 			info.VoteList = lastGameStatus.Votes
@@ -63,6 +72,14 @@ func NewInfo(agent *Agent, gameStatus *GameStatus, lastGameStatus *GameStatus, s
 			info.AttackVoteList = lastGameStatus.AttackVotes
 		}
 	}
+	if settings.VoteMode == VM_SEQUENTIAL {
+		info.CastVoteList = gameStatus.Votes
+	}
+	info.DeclaredVoteList = gameStatus.DeclaredVotes
+	info.VoteCandidates = gameStatus.VoteCandidates
+	if agent.Role == R_WEREWOLF {
+		info.AttackVoteCandidates = gameStatus.AttackVoteCandidates
+	}
 	info.TalkList = gameStatus.Talks
 	if agent.Role == R_WEREWOLF {
 		info.WhisperList = gameStatus.Whispers
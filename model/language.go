@@ -0,0 +1,22 @@
+package model
+
+type Language string
+
+const (
+	L_JA Language = "ja"
+	L_EN Language = "en"
+)
+
+func LanguageFromString(s string) Language {
+	switch s {
+	case "ja":
+		return L_JA
+	case "en":
+		return L_EN
+	}
+	return ""
+}
+
+func (l Language) String() string {
+	return string(l)
+}
@@ -7,6 +7,7 @@ import (
 type MatchWeight struct {
 	RoleIdxs map[Role][]int `json:"role_idxs"`
 	Weight   float64        `json:"weight"`
+	Reason   string         `json:"reason,omitempty"`
 }
 
 func (mw MatchWeight) Equal(other MatchWeight) bool {
@@ -46,8 +47,10 @@ func (mw MatchWeight) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		RoleIdxs map[string][]int `json:"role_idxs"`
 		Weight   float64          `json:"weight"`
+		Reason   string           `json:"reason,omitempty"`
 	}{
 		RoleIdxs: roleIdxs,
 		Weight:   mw.Weight,
+		Reason:   mw.Reason,
 	})
 }
@@ -1,6 +1,8 @@
 package model
 
 type Packet struct {
+	GameID         string    `json:"gameId,omitempty"`
+	RequestID      int       `json:"requestId"`
 	Request        *Request  `json:"request"`
 	Info           *Info     `json:"info,omitempty"`
 	Settings       *Settings `json:"setting,omitempty"`
@@ -0,0 +1,7 @@
+package model
+
+type Persona struct {
+	Name    string `yaml:"name" json:"name"`
+	Style   string `yaml:"style" json:"style"`
+	Profile string `yaml:"profile" json:"profile"`
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Pong などの制御フレームは読み込み中にのみ処理されるため、接続ごとに常に読み込みを続ける
+type Receiver struct {
+	conn      *websocket.Conn
+	messages  chan []byte
+	done      chan struct{}
+	err       error
+	requestID int
+	canceled  chan struct{}
+	cancel    sync.Once
+}
+
+func NewReceiver(conn *websocket.Conn) *Receiver {
+	r := &Receiver{
+		conn:     conn,
+		messages: make(chan []byte, 16),
+		done:     make(chan struct{}),
+		canceled: make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+func (r *Receiver) run() {
+	defer close(r.done)
+	for {
+		_, message, err := r.conn.ReadMessage()
+		if err != nil {
+			r.err = err
+			return
+		}
+		select {
+		case r.messages <- message:
+		default:
+			slog.Warn("受信バッファが一杯のため、メッセージを破棄しました", "remote_addr", r.conn.RemoteAddr().String(), "message", string(message))
+		}
+	}
+}
+
+// 応答のない接続は読み込み期限を過ぎて切断されたものとして扱う
+func (r *Receiver) StartHeartbeat(interval time.Duration, timeout time.Duration) {
+	r.conn.SetReadDeadline(time.Now().Add(interval + timeout))
+	r.conn.SetPongHandler(func(string) error {
+		return r.conn.SetReadDeadline(time.Now().Add(interval + timeout))
+	})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout)); err != nil {
+					slog.Warn("Pingの送信に失敗しました", "remote_addr", r.conn.RemoteAddr().String(), "error", err)
+					return
+				}
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+func (r *Receiver) NextRequestID() int {
+	r.requestID++
+	return r.requestID
+}
+
+// リクエストの送信前に受信済みのメッセージは以前のリクエストへの遅れたレスポンスであるため破棄する
+func (r *Receiver) DiscardStale() []string {
+	discarded := make([]string, 0)
+	for {
+		select {
+		case message := <-r.messages:
+			discarded = append(discarded, string(message))
+		default:
+			return discarded
+		}
+	}
+}
+
+func (r *Receiver) Messages() <-chan []byte {
+	return r.messages
+}
+
+func (r *Receiver) Done() <-chan struct{} {
+	return r.done
+}
+
+// Done が閉じられた後にのみ有効
+func (r *Receiver) Err() error {
+	return r.err
+}
+
+// 待機中および以降のレスポンスの待機を取り消す
+func (r *Receiver) Cancel() {
+	r.cancel.Do(func() {
+		close(r.canceled)
+	})
+}
+
+func (r *Receiver) Canceled() <-chan struct{} {
+	return r.canceled
+}
+
+func (r *Receiver) IsAlive() bool {
+	select {
+	case <-r.done:
+		return false
+	default:
+		return true
+	}
+}
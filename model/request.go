@@ -21,6 +21,9 @@ var (
 	R_VOTE = Request{
 		Type:            "VOTE",
 		RequireResponse: true}
+	R_DECLARE_VOTE = Request{
+		Type:            "DECLARE_VOTE",
+		RequireResponse: true}
 	R_DIVINE = Request{
 		Type:            "DIVINE",
 		RequireResponse: true}
@@ -62,6 +65,8 @@ func RequestFromString(s string) Request {
 		return R_WHISPER
 	case "VOTE":
 		return R_VOTE
+	case "DECLARE_VOTE":
+		return R_DECLARE_VOTE
 	case "DIVINE":
 		return R_DIVINE
 	case "GUARD":
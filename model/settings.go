@@ -3,23 +3,34 @@ package model
 import (
 	"encoding/json"
 	"errors"
+	"strings"
+	"time"
 )
 
 type Settings struct {
-	PlayerNum        int          `json:"playerNum"`
-	RoleNumMap       map[Role]int `json:"roleNumMap"`
-	MaxTalk          int          `json:"maxTalk"`
-	MaxTalkTurn      int          `json:"maxTalkTurn"`
-	MaxWhisper       int          `json:"maxWhisper"`
-	MaxWhisperTurn   int          `json:"maxWhisperTurn"`
-	MaxSkip          int          `json:"maxSkip"`
-	IsEnableNoAttack bool         `json:"isEnableNoAttack"`
-	IsVoteVisible    bool         `json:"isVoteVisible"`
-	IsTalkOnFirstDay bool         `json:"isTalkOnFirstDay"`
-	ResponseTimeout  int          `json:"responseTimeout"`
-	ActionTimeout    int          `json:"actionTimeout"`
-	MaxRevote        int          `json:"maxRevote"`
-	MaxAttackRevote  int          `json:"maxAttackRevote"`
+	PlayerNum           int                 `json:"playerNum"`
+	RoleNumMap          map[Role]int        `json:"roleNumMap"`
+	Language            Language            `json:"language"`
+	MaxTalk             int                 `json:"maxTalk"`
+	MaxTalkTurn         int                 `json:"maxTalkTurn"`
+	MaxWhisper          int                 `json:"maxWhisper"`
+	MaxWhisperTurn      int                 `json:"maxWhisperTurn"`
+	MaxSkip             int                 `json:"maxSkip"`
+	IsEnableNoAttack    bool                `json:"isEnableNoAttack"`
+	IsVoteVisible       bool                `json:"isVoteVisible"`
+	VoteMode            VoteMode            `json:"voteMode"`
+	IsVoteDeclaration   bool                `json:"isVoteDeclaration"`
+	IsRunoffVote        bool                `json:"isRunoffVote"`
+	VoteTieBreak        TieBreak            `json:"voteTieBreak"`
+	AttackTieBreak      TieBreak            `json:"attackTieBreak"`
+	IsTalkOnFirstDay    bool                `json:"isTalkOnFirstDay"`
+	ResponseTimeout     int                 `json:"responseTimeout"`
+	ActionTimeout       int                 `json:"actionTimeout"`
+	ActionTimeoutMap    map[string]int      `json:"actionTimeoutMap,omitempty"`
+	VoteTimeoutFallback VoteFallback        `json:"voteTimeoutFallback"`
+	FallbackMap         map[string]Fallback `json:"fallbackMap"`
+	MaxRevote           int                 `json:"maxRevote"`
+	MaxAttackRevote     int                 `json:"maxAttackRevote"`
 }
 
 func NewSettings(config Config) (*Settings, error) {
@@ -27,24 +38,78 @@ func NewSettings(config Config) (*Settings, error) {
 	if roleNumMap == nil {
 		return nil, errors.New("対応する役職の人数がありません")
 	}
+	voteMode := VoteModeFromString(config.Game.Vote.Mode)
+	if voteMode == "" {
+		voteMode = VM_SIMULTANEOUS
+	}
+	voteTieBreak := TieBreakFromString(config.Game.Vote.TieBreak)
+	if voteTieBreak == "" {
+		voteTieBreak = TB_RANDOM
+	}
+	attackTieBreak := TieBreakFromString(config.Game.Attack.TieBreak)
+	if attackTieBreak == "" {
+		if config.Game.Attack.AllowNoTarget {
+			attackTieBreak = TB_NONE
+		} else {
+			attackTieBreak = TB_RANDOM
+		}
+	}
+	fallbackMap := make(map[string]Fallback)
+	for _, request := range FallbackRequests {
+		fallbackMap[request.Type] = FB_ABSTAIN
+	}
+	for key, fallback := range config.Game.Fallback {
+		if f := FallbackFromString(fallback); f != "" {
+			fallbackMap[strings.ToUpper(key)] = f
+		}
+	}
+	var actionTimeoutMap map[string]int
+	if len(config.Game.Timeout.PerRequest) > 0 {
+		actionTimeoutMap = make(map[string]int)
+		for key, timeout := range config.Game.Timeout.PerRequest {
+			actionTimeoutMap[strings.ToUpper(key)] = int(timeout.Milliseconds())
+		}
+	}
+	// 投票のタイムアウト時の処理が未指定の場合は、投票の代わりの行動に従う
+	voteTimeoutFallback := VoteFallbackFromString(config.Game.Vote.TimeoutFallback)
+	if voteTimeoutFallback == "" {
+		voteTimeoutFallback = fallbackMap[R_VOTE.Type]
+	}
 	return &Settings{
-		PlayerNum:        config.Game.AgentCount,
-		RoleNumMap:       roleNumMap,
-		MaxTalk:          config.Game.Talk.MaxCount.PerAgent,
-		MaxTalkTurn:      config.Game.Talk.MaxCount.PerDay,
-		MaxWhisper:       config.Game.Whisper.MaxCount.PerAgent,
-		MaxWhisperTurn:   config.Game.Whisper.MaxCount.PerDay,
-		MaxSkip:          config.Game.Skip.MaxCount,
-		IsEnableNoAttack: config.Game.Attack.AllowNoTarget,
-		IsVoteVisible:    config.Game.VoteVisibility,
-		IsTalkOnFirstDay: config.Game.TalkOnFirstDay,
-		ResponseTimeout:  int(config.Game.Timeout.Response.Milliseconds()),
-		ActionTimeout:    int(config.Game.Timeout.Action.Milliseconds()),
-		MaxRevote:        config.Game.Vote.MaxCount,
-		MaxAttackRevote:  config.Game.Attack.MaxCount,
+		PlayerNum:           config.Game.AgentCount,
+		RoleNumMap:          roleNumMap,
+		Language:            LanguageFromString(config.Game.Language),
+		MaxTalk:             config.Game.Talk.MaxCount.PerAgent,
+		MaxTalkTurn:         config.Game.Talk.MaxCount.PerDay,
+		MaxWhisper:          config.Game.Whisper.MaxCount.PerAgent,
+		MaxWhisperTurn:      config.Game.Whisper.MaxCount.PerDay,
+		MaxSkip:             config.Game.Skip.MaxCount,
+		IsEnableNoAttack:    config.Game.Attack.AllowNoTarget,
+		IsVoteVisible:       config.Game.VoteVisibility,
+		VoteMode:            voteMode,
+		IsVoteDeclaration:   config.Game.Vote.Declaration,
+		IsRunoffVote:        config.Game.Vote.Runoff,
+		VoteTieBreak:        voteTieBreak,
+		AttackTieBreak:      attackTieBreak,
+		IsTalkOnFirstDay:    config.Game.TalkOnFirstDay,
+		ResponseTimeout:     int(config.Game.Timeout.Response.Milliseconds()),
+		ActionTimeout:       int(config.Game.Timeout.Action.Milliseconds()),
+		ActionTimeoutMap:    actionTimeoutMap,
+		VoteTimeoutFallback: voteTimeoutFallback,
+		FallbackMap:         fallbackMap,
+		MaxRevote:           config.Game.Vote.MaxCount,
+		MaxAttackRevote:     config.Game.Attack.MaxCount,
 	}, nil
 }
 
+// リクエストの種類ごとのタイムアウトが設定されていない場合は共通のタイムアウトを使用する
+func (s Settings) ActionTimeoutFor(request Request) time.Duration {
+	if timeout, exists := s.ActionTimeoutMap[request.Type]; exists {
+		return time.Duration(timeout) * time.Millisecond
+	}
+	return time.Duration(s.ActionTimeout) * time.Millisecond
+}
+
 func (s Settings) MarshalJSON() ([]byte, error) {
 	roleNumMap := make(map[string]int)
 	for k, v := range s.RoleNumMap {
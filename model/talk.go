@@ -3,11 +3,12 @@ package model
 import "encoding/json"
 
 type Talk struct {
-	Idx   int    `json:"idx"`
-	Day   int    `json:"day"`
-	Turn  int    `json:"turn"`
-	Agent Agent  `json:"agent"`
-	Text  string `json:"text"`
+	Idx      int      `json:"idx"`
+	Day      int      `json:"day"`
+	Turn     int      `json:"turn"`
+	Agent    Agent    `json:"agent"`
+	Text     string   `json:"text"`
+	Language Language `json:"language,omitempty"`
 }
 
 func (t Talk) MarshalJSON() ([]byte, error) {
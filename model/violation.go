@@ -0,0 +1,39 @@
+package model
+
+type Violation string
+
+const (
+	V_INVALID_UTF8      Violation = "INVALID_UTF8"
+	V_CONTROL_CHARACTER Violation = "CONTROL_CHARACTER"
+	V_MAX_CHARACTERS    Violation = "MAX_CHARACTERS"
+	V_MAX_BYTES         Violation = "MAX_BYTES"
+	V_BANNED_PATTERN    Violation = "BANNED_PATTERN"
+)
+
+func (v Violation) String() string {
+	return string(v)
+}
+
+type ViolationAction string
+
+const (
+	VA_TRUNCATE ViolationAction = "truncate"
+	VA_SKIP     ViolationAction = "skip"
+	VA_ERROR    ViolationAction = "error"
+)
+
+func ViolationActionFromString(s string) ViolationAction {
+	switch s {
+	case "truncate":
+		return VA_TRUNCATE
+	case "skip":
+		return VA_SKIP
+	case "error":
+		return VA_ERROR
+	}
+	return ""
+}
+
+func (v Violation) IsSanitization() bool {
+	return v == V_INVALID_UTF8 || v == V_CONTROL_CHARACTER
+}
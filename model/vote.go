@@ -5,3 +5,57 @@ type Vote struct {
 	Agent  Agent `json:"agent"`
 	Target Agent `json:"target"`
 }
+
+type VoteMode string
+
+const (
+	VM_SIMULTANEOUS VoteMode = "simultaneous"
+	VM_SEQUENTIAL   VoteMode = "sequential"
+)
+
+func VoteModeFromString(s string) VoteMode {
+	switch s {
+	case "simultaneous":
+		return VM_SIMULTANEOUS
+	case "sequential":
+		return VM_SEQUENTIAL
+	}
+	return ""
+}
+
+type TieBreak string
+
+const (
+	TB_RANDOM TieBreak = "random"
+	TB_NONE   TieBreak = "none"
+	TB_RUNOFF TieBreak = "runoff"
+	TB_ALL    TieBreak = "all"
+)
+
+func TieBreakFromString(s string) TieBreak {
+	switch s {
+	case "random":
+		return TB_RANDOM
+	case "none":
+		return TB_NONE
+	case "runoff":
+		return TB_RUNOFF
+	case "all":
+		return TB_ALL
+	}
+	return ""
+}
+
+// 投票のタイムアウト時の処理は、アクションごとの代わりの行動と同じ方針を使用する
+type VoteFallback = Fallback
+
+const (
+	VF_ABSTAIN      = FB_ABSTAIN
+	VF_RANDOM       = FB_RANDOM
+	VF_EXCLUDE_SELF = FB_EXCLUDE_SELF
+	VF_LAST         = FB_LAST
+)
+
+func VoteFallbackFromString(s string) VoteFallback {
+	return FallbackFromString(s)
+}
@@ -3,9 +3,12 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/kano-lab/aiwolf-nlp-server/model"
@@ -16,6 +19,26 @@ type AnalysisService struct {
 	outputDir        string
 	templateFilename string
 	endGameStatus    map[string]bool
+	maxGames         int
+	maxAge           time.Duration
+	teamTotals       map[string]*teamTotals
+	mu               sync.RWMutex
+}
+
+// 終了したゲームのチームごとの通算成績は、ゲームデータをメモリから削除しても保持する
+type teamTotals struct {
+	games        int
+	stats        map[string]map[string]int
+	latencySum   int64
+	latencyCount int64
+}
+
+type teamSummary struct {
+	agentRoles    map[string]model.Role
+	erroredAgents map[string]bool
+	latencySum    int64
+	latencyCount  int64
+	errors        []map[string]interface{}
 }
 
 type GameData struct {
@@ -24,37 +47,154 @@ type GameData struct {
 	agents       []interface{}
 	winSide      model.Team
 	entries      []interface{}
+	timeline     []interface{}
 	timestampMap map[string]int64
 	requestMap   map[string]interface{}
+	startedAt    time.Time
+	endedAt      time.Time
+	persisted    bool
 }
 
 func NewAnalysisService(config model.Config) *AnalysisService {
-	return &AnalysisService{
+	a := &AnalysisService{
 		gamesData:        make(map[string]*GameData),
 		outputDir:        config.AnalysisService.OutputDir,
 		templateFilename: config.AnalysisService.Filename,
 		endGameStatus:    make(map[string]bool),
+		maxGames:         config.AnalysisService.Retention.MaxGames,
+		maxAge:           config.AnalysisService.Retention.MaxAge,
+		teamTotals:       make(map[string]*teamTotals),
+	}
+	if a.maxAge > 0 {
+		go a.runEviction()
+	}
+	return a
+}
+
+// ゲームが終了しない間も保持期間を過ぎたゲームデータを削除する
+func (a *AnalysisService) runEviction() {
+	ticker := time.NewTicker(min(a.maxAge, time.Minute))
+	defer ticker.Stop()
+	for range ticker.C {
+		a.mu.Lock()
+		a.evictGameData()
+		a.mu.Unlock()
 	}
 }
 
-func (a *AnalysisService) TrackStartGame(id string, agents []*model.Agent) {
+func (a *AnalysisService) HandleEvent(event model.Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch e := event.(type) {
+	case model.GameStartedEvent:
+		a.trackStartGame(e.GameID, e.Agents, e.Timestamp)
+	case model.GameEndedEvent:
+		a.appendTimeline(e.GameID, e.Day, "game_ended", map[string]interface{}{
+			"win_side": e.WinSide,
+		})
+		a.trackEndGame(e.GameID, e.WinSide)
+	case model.DayStartedEvent:
+		a.appendTimeline(e.GameID, e.Day, "day_started", nil)
+	case model.TalkSpokenEvent:
+		a.appendTimeline(e.GameID, e.Day, strings.ToLower(e.Request.Type), map[string]interface{}{
+			"idx":      e.Talk.Idx,
+			"turn":     e.Talk.Turn,
+			"agent":    e.Talk.Agent,
+			"text":     e.Talk.Text,
+			"skip":     e.Talk.Text == model.T_SKIP || e.Talk.Text == model.T_FORCE_SKIP,
+			"over":     e.Talk.Text == model.T_OVER,
+			"decision": e.Decision,
+		})
+	case model.VoteCastEvent:
+		kind := strings.ToLower(e.Request.Type)
+		if e.Request == model.R_ATTACK {
+			kind = "attack_vote"
+		}
+		a.appendTimeline(e.GameID, e.Day, kind, map[string]interface{}{
+			"agent":    e.Vote.Agent,
+			"target":   e.Vote.Target,
+			"decision": e.Decision,
+		})
+	case model.ExecutedEvent:
+		a.appendTimeline(e.GameID, e.Day, "execute", map[string]interface{}{
+			"agent": e.Agent,
+		})
+	case model.DivinedEvent:
+		a.appendTimeline(e.GameID, e.Day, "divine", map[string]interface{}{
+			"agent":    e.Judge.Agent,
+			"target":   e.Judge.Target,
+			"result":   e.Judge.Result,
+			"decision": e.Decision,
+		})
+	case model.GuardedEvent:
+		a.appendTimeline(e.GameID, e.Day, "guard", map[string]interface{}{
+			"agent":    e.Guard.Agent,
+			"target":   e.Guard.Target,
+			"decision": e.Decision,
+		})
+	case model.AttackedEvent:
+		a.appendTimeline(e.GameID, e.Day, "attack", map[string]interface{}{
+			"agent":     e.Agent,
+			"succeeded": e.Succeeded,
+		})
+	case model.RequestStartedEvent:
+		a.trackStartRequest(e.GameID, e.Agent, e.Packet)
+	case model.RequestEndedEvent:
+		a.trackEndRequest(e.GameID, e.Agent, e.RequestID, e.Response, e.Error)
+	case model.TalkViolatedEvent:
+		a.appendEntry(e.GameID, map[string]interface{}{
+			"agent":        e.Agent.String(),
+			"timestamp":    e.Timestamp.UnixNano() / 1e6,
+			"request_type": e.Request.Type,
+			"violations":   e.Violations,
+			"action":       e.Action,
+			"text":         e.Text,
+		})
+	case model.LanguageMismatchedEvent:
+		a.appendEntry(e.GameID, map[string]interface{}{
+			"agent":             e.Talk.Agent.String(),
+			"timestamp":         e.Timestamp.UnixNano() / 1e6,
+			"request_type":      e.Request.Type,
+			"talk_idx":          e.Talk.Idx,
+			"language":          e.Talk.Language,
+			"expected_language": e.Expected,
+			"text":              e.Talk.Text,
+		})
+	case model.TieBrokenEvent:
+		a.appendEntry(e.GameID, map[string]interface{}{
+			"timestamp":    e.Timestamp.UnixNano() / 1e6,
+			"day":          e.Day,
+			"request_type": e.Request.Type,
+			"tie_break":    e.TieBreak,
+			"candidates":   e.Candidates,
+			"resolved":     e.Resolved,
+		})
+	}
+}
+
+func (a *AnalysisService) trackStartGame(id string, agents []model.Agent, startedAt time.Time) {
 	gameData := &GameData{
 		id:           id,
 		agents:       make([]interface{}, 0),
 		entries:      make([]interface{}, 0),
+		timeline:     make([]interface{}, 0),
 		timestampMap: make(map[string]int64),
 		requestMap:   make(map[string]interface{}),
 		winSide:      model.T_NONE,
+		startedAt:    startedAt,
 	}
 	for _, agent := range agents {
-		gameData.agents = append(gameData.agents,
-			map[string]interface{}{
-				"idx":  agent.Idx,
-				"team": agent.Team,
-				"name": agent.Name,
-				"role": agent.Role,
-			},
-		)
+		agentData := map[string]interface{}{
+			"agent": agent.String(),
+			"idx":   agent.Idx,
+			"team":  agent.Team,
+			"name":  agent.Name,
+			"role":  agent.Role,
+		}
+		if agent.Persona != nil {
+			agentData["persona"] = agent.Persona
+		}
+		gameData.agents = append(gameData.agents, agentData)
 	}
 	filename := strings.ReplaceAll(a.templateFilename, "{game_id}", gameData.id)
 	filename = strings.ReplaceAll(filename, "{timestamp}", fmt.Sprintf("%d", time.Now().Unix()))
@@ -77,26 +217,82 @@ func (a *AnalysisService) TrackStartGame(id string, agents []*model.Agent) {
 	a.endGameStatus[id] = false
 }
 
-func (a *AnalysisService) TrackEndGame(id string, winSide model.Team) {
+func (a *AnalysisService) trackEndGame(id string, winSide model.Team) {
 	if gameData, exists := a.gamesData[id]; exists {
 		gameData.winSide = winSide
+		gameData.endedAt = time.Now()
 		a.endGameStatus[id] = true
+		a.addTeamTotals(gameData)
 		a.saveGameData(id)
+		a.evictGameData()
+	}
+}
+
+func (a *AnalysisService) addTeamTotals(gameData *GameData) {
+	for _, team := range gameData.teams() {
+		totals, exists := a.teamTotals[team]
+		if !exists {
+			totals = &teamTotals{stats: make(map[string]map[string]int)}
+			a.teamTotals[team] = totals
+		}
+		summary := gameData.summarizeTeam(team)
+		totals.games++
+		totals.latencySum += summary.latencySum
+		totals.latencyCount += summary.latencyCount
+		for agent, role := range summary.agentRoles {
+			if _, exists := totals.stats[role.Name]; !exists {
+				totals.stats[role.Name] = map[string]int{"games": 0, "wins": 0, "losses": 0, "errors": 0}
+			}
+			totals.stats[role.Name]["games"]++
+			switch gameData.winSide {
+			case role.Team:
+				totals.stats[role.Name]["wins"]++
+			case model.T_NONE:
+			default:
+				totals.stats[role.Name]["losses"]++
+			}
+			if summary.erroredAgents[agent] {
+				totals.stats[role.Name]["errors"]++
+			}
+		}
+	}
+}
+
+func (a *AnalysisService) evictGameData() {
+	finished := make([]*GameData, 0)
+	for id, gameData := range a.gamesData {
+		if a.endGameStatus[id] && gameData.persisted {
+			finished = append(finished, gameData)
+		}
+	}
+	slices.SortFunc(finished, func(x, y *GameData) int {
+		return x.endedAt.Compare(y.endedAt)
+	})
+	for i, gameData := range finished {
+		expired := a.maxAge > 0 && time.Since(gameData.endedAt) > a.maxAge
+		overflowed := a.maxGames > 0 && len(finished)-i > a.maxGames
+		if !expired && !overflowed {
+			continue
+		}
+		delete(a.gamesData, gameData.id)
+		delete(a.endGameStatus, gameData.id)
+		slog.Info("ゲームデータをメモリから削除しました", "id", gameData.id)
 	}
 }
 
-func (a *AnalysisService) TrackStartRequest(id string, agent model.Agent, packet model.Packet) {
+func (a *AnalysisService) trackStartRequest(id string, agent model.Agent, packet model.Packet) {
 	if gameData, exists := a.gamesData[id]; exists {
 		gameData.timestampMap[agent.Name] = time.Now().UnixNano()
 		gameData.requestMap[agent.Name] = packet
 	}
 }
 
-func (a *AnalysisService) TrackEndRequest(id string, agent model.Agent, response string, err error) {
+func (a *AnalysisService) trackEndRequest(id string, agent model.Agent, requestID int, response string, err error) {
 	if gameData, exists := a.gamesData[id]; exists {
 		timestamp := time.Now().UnixNano()
 		entry := map[string]interface{}{
 			"agent":              agent.String(),
+			"request_id":         requestID,
 			"request_timestamp":  gameData.timestampMap[agent.Name] / 1e6,
 			"response_timestamp": timestamp / 1e6,
 		}
@@ -120,6 +316,78 @@ func (a *AnalysisService) TrackEndRequest(id string, agent model.Agent, response
 	}
 }
 
+func (a *AnalysisService) appendEntry(id string, entry map[string]interface{}) {
+	if gameData, exists := a.gamesData[id]; exists {
+		gameData.entries = append(gameData.entries, entry)
+
+		a.saveGameData(id)
+	}
+}
+
+func (g *GameData) teams() []string {
+	teams := make([]string, 0)
+	for _, agent := range g.agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			if team, ok := agentMap["team"].(string); ok && !slices.Contains(teams, team) {
+				teams = append(teams, team)
+			}
+		}
+	}
+	return teams
+}
+
+func (g *GameData) summarizeTeam(team string) teamSummary {
+	summary := teamSummary{
+		agentRoles:    make(map[string]model.Role),
+		erroredAgents: make(map[string]bool),
+		errors:        make([]map[string]interface{}, 0),
+	}
+	for _, agent := range g.agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok && agentMap["team"] == team {
+			summary.agentRoles[agentMap["agent"].(string)] = agentMap["role"].(model.Role)
+		}
+	}
+	for _, entry := range g.entries {
+		entryMap, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		agent, _ := entryMap["agent"].(string)
+		if _, exists := summary.agentRoles[agent]; !exists {
+			continue
+		}
+		requestTimestamp, ok := entryMap["request_timestamp"].(int64)
+		if !ok {
+			continue
+		}
+		responseTimestamp := entryMap["response_timestamp"].(int64)
+		summary.latencySum += responseTimestamp - requestTimestamp
+		summary.latencyCount++
+		if err, exists := entryMap["error"]; exists {
+			summary.erroredAgents[agent] = true
+			summary.errors = append(summary.errors, map[string]interface{}{
+				"game_id":   g.id,
+				"agent":     agent,
+				"timestamp": responseTimestamp,
+				"request":   entryMap["request"],
+				"error":     err,
+			})
+		}
+	}
+	return summary
+}
+
+func (a *AnalysisService) appendTimeline(id string, day int, kind string, data map[string]interface{}) {
+	if gameData, exists := a.gamesData[id]; exists {
+		if data == nil {
+			data = make(map[string]interface{})
+		}
+		data["type"] = kind
+		data["day"] = day
+		gameData.timeline = append(gameData.timeline, data)
+	}
+}
+
 func (a *AnalysisService) saveGameData(id string) {
 	if gameData, exists := a.gamesData[id]; exists {
 		game := map[string]interface{}{
@@ -127,6 +395,7 @@ func (a *AnalysisService) saveGameData(id string) {
 			"win_side": gameData.winSide,
 			"agents":   gameData.agents,
 			"entries":  gameData.entries,
+			"timeline": gameData.timeline,
 		}
 		jsonData, err := json.Marshal(game)
 		if err != nil {
@@ -141,6 +410,7 @@ func (a *AnalysisService) saveGameData(id string) {
 			return
 		}
 		defer file.Close()
-		file.Write(jsonData)
+		_, err = file.Write(jsonData)
+		gameData.persisted = err == nil
 	}
 }
@@ -1,54 +1,261 @@
 package service
 
 import (
+	"crypto/subtle"
 	"maps"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/util"
+	"github.com/kano-lab/aiwolf-nlp-server/web"
+)
+
+const (
+	recentErrorCount = 20
+	defaultGameLimit = 100
+	maxGameLimit     = 1000
 )
 
 type ApiService struct {
 	analysisService    *AnalysisService
 	publishRunningGame bool
+	revealToken        string
+	viewerPath         string
 }
 
+// 進行中のゲームで、人狼・占い師・騎士のみが知り得るタイムラインの種類
+var secretTimelineTypes = []string{"whisper", "attack_vote", "divine", "guard"}
+
 func NewApiService(analysisService *AnalysisService, config model.Config) *ApiService {
-	return &ApiService{
+	api := &ApiService{
 		analysisService:    analysisService,
 		publishRunningGame: config.ApiService.PublishRunningGame,
+		revealToken:        config.ApiService.RevealToken,
+	}
+	if config.ApiService.Viewer.Enable {
+		api.viewerPath = config.ApiService.Viewer.Path
 	}
+	return api
 }
 
 func (api *ApiService) RegisterRoutes(router *gin.Engine) {
 	router.GET("/api/games", api.handleGameIDs)
 	router.GET("/api/game", api.handleGameData)
 	router.GET("/api/teams", api.handleTeams)
+	router.GET("/api/teams/:team", api.handleTeam)
+	router.GET("/api/timeline", api.handleTimeline)
+	if api.viewerPath != "" {
+		router.GET(api.viewerPath, func(c *gin.Context) {
+			c.Data(200, "text/html; charset=utf-8", web.Viewer)
+		})
+	}
+}
+
+func (api *ApiService) message(c *gin.Context, message string) string {
+	return util.TranslateForAcceptLanguage(message, c.GetHeader("Accept-Language"))
+}
+
+// 進行中のゲームの役職や秘匿情報の公開は、reveal_token と一致するトークンが送られた場合のみ許可する
+func (api *ApiService) revealAllowed(c *gin.Context) bool {
+	if api.revealToken == "" {
+		return false
+	}
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(api.revealToken)) == 1
+}
+
+// 役職や秘匿情報を返すかどうかを判定する
+// 終了したゲームは常に公開し、進行中のゲームは reveal=true が指定され、かつ許可された場合のみ公開する
+func (api *ApiService) reveal(c *gin.Context, isFinished bool) (bool, bool) {
+	if isFinished {
+		return true, true
+	}
+	if c.Query("reveal") != "true" {
+		return false, true
+	}
+	allowed := api.revealAllowed(c)
+	return allowed, allowed
+}
+
+func redactAgents(agents []interface{}) []interface{} {
+	redacted := make([]interface{}, 0, len(agents))
+	for _, agent := range agents {
+		if data, ok := agent.(map[string]interface{}); ok {
+			data = maps.Clone(data)
+			delete(data, "role")
+			agent = data
+		}
+		redacted = append(redacted, agent)
+	}
+	return redacted
+}
+
+// 秘匿情報を除いたタイムラインを返す
+// 護衛の成否が分からないよう、襲撃は犠牲者がいた場合のみ対象を残す
+func redactTimeline(timeline []interface{}) []interface{} {
+	redacted := make([]interface{}, 0, len(timeline))
+	for _, event := range timeline {
+		data, ok := event.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _ := data["type"].(string)
+		if slices.Contains(secretTimelineTypes, kind) {
+			continue
+		}
+		if agent, _ := data["agent"].(*model.Agent); kind == "attack" && (data["succeeded"] != true || agent == nil) {
+			data = gin.H{"type": "attack", "day": data["day"], "succeeded": false}
+		}
+		redacted = append(redacted, data)
+	}
+	return redacted
 }
 
 func (api *ApiService) handleGameIDs(c *gin.Context) {
-	if len(api.analysisService.gamesData) == 0 {
-		c.JSON(200, gin.H{"games": []string{}})
+	team := c.Query("team")
+	status := c.Query("status")
+	if status != "" && status != "running" && status != "finished" {
+		c.JSON(400, gin.H{"error": api.message(c, "invalid status")})
 		return
 	}
-	c.JSON(200, gin.H{
-		"games": slices.Collect(maps.Keys(api.analysisService.gamesData)),
-	})
+	winSide := c.Query("win_side")
+	if winSide != "" && model.TeamFromString(winSide) == model.T_NONE && winSide != string(model.T_NONE) {
+		c.JSON(400, gin.H{"error": api.message(c, "invalid win_side")})
+		return
+	}
+	var since, until time.Time
+	if value := c.Query("since"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(400, gin.H{"error": api.message(c, "invalid since")})
+			return
+		}
+		since = t
+	}
+	if value := c.Query("until"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(400, gin.H{"error": api.message(c, "invalid until")})
+			return
+		}
+		until = t
+	}
+	order := c.DefaultQuery("order", "desc")
+	if order != "asc" && order != "desc" {
+		c.JSON(400, gin.H{"error": api.message(c, "invalid order")})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultGameLimit)))
+	if err != nil || limit <= 0 || limit > maxGameLimit {
+		c.JSON(400, gin.H{"error": api.message(c, "invalid limit")})
+		return
+	}
+	var cursor *gameCursor
+	if value := c.Query("cursor"); value != "" {
+		cursor = parseGameCursor(value)
+		if cursor == nil {
+			c.JSON(400, gin.H{"error": api.message(c, "invalid cursor")})
+			return
+		}
+	}
+
+	api.analysisService.mu.RLock()
+	defer api.analysisService.mu.RUnlock()
+	cursors := make([]gameCursor, 0)
+	for id, data := range api.analysisService.gamesData {
+		isFinished := api.analysisService.endGameStatus[id]
+		if status == "running" && isFinished || status == "finished" && !isFinished {
+			continue
+		}
+		if winSide != "" && (!isFinished || string(data.winSide) != winSide) {
+			continue
+		}
+		if team != "" && !slices.Contains(data.teams(), team) {
+			continue
+		}
+		if !since.IsZero() && data.startedAt.Before(since) || !until.IsZero() && !data.startedAt.Before(until) {
+			continue
+		}
+		cursors = append(cursors, gameCursor{startedAt: data.startedAt, id: id})
+	}
+	compare := func(a, b gameCursor) int {
+		if order == "desc" {
+			return b.compare(a)
+		}
+		return a.compare(b)
+	}
+	slices.SortFunc(cursors, compare)
+	if cursor != nil {
+		idx, found := slices.BinarySearchFunc(cursors, *cursor, compare)
+		if found {
+			idx++
+		}
+		cursors = cursors[idx:]
+	}
+
+	ids := make([]string, 0, limit)
+	for _, cursor := range cursors[:min(limit, len(cursors))] {
+		ids = append(ids, cursor.id)
+	}
+	resp := gin.H{"games": ids}
+	if len(cursors) > limit {
+		resp["next_cursor"] = cursors[limit-1].String()
+	}
+	c.JSON(200, resp)
+}
+
+type gameCursor struct {
+	startedAt time.Time
+	id        string
+}
+
+func parseGameCursor(value string) *gameCursor {
+	timestamp, id, found := strings.Cut(value, "_")
+	if !found || id == "" {
+		return nil
+	}
+	nanos, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &gameCursor{startedAt: time.Unix(0, nanos), id: id}
+}
+
+func (g gameCursor) compare(other gameCursor) int {
+	if c := g.startedAt.Compare(other.startedAt); c != 0 {
+		return c
+	}
+	return strings.Compare(g.id, other.id)
+}
+
+func (g gameCursor) String() string {
+	return strconv.FormatInt(g.startedAt.UnixNano(), 10) + "_" + g.id
 }
 
 func (api *ApiService) handleGameData(c *gin.Context) {
 	id := c.Query("id")
 	if id == "" {
-		c.JSON(400, gin.H{"error": "id is required"})
+		c.JSON(400, gin.H{"error": api.message(c, "id is required")})
 		return
 	}
+	api.analysisService.mu.RLock()
+	defer api.analysisService.mu.RUnlock()
 	data, exists := api.analysisService.gamesData[id]
 	if !exists {
-		c.JSON(404, gin.H{"error": "game not found"})
+		c.JSON(404, gin.H{"error": api.message(c, "game not found")})
 		return
 	}
 	if !api.publishRunningGame && !api.analysisService.endGameStatus[id] {
-		c.JSON(403, gin.H{"error": "game is running"})
+		c.JSON(403, gin.H{"error": api.message(c, "game is running")})
+		return
+	}
+	// リクエストのパケットには役職や占い結果が含まれるため、進行中のゲームは公開が許可された場合のみ返す
+	if !api.analysisService.endGameStatus[id] && !api.revealAllowed(c) {
+		c.JSON(403, gin.H{"error": api.message(c, "reveal is not allowed")})
 		return
 	}
 	resp := gin.H{
@@ -60,28 +267,158 @@ func (api *ApiService) handleGameData(c *gin.Context) {
 	c.JSON(200, resp)
 }
 
-func (api *ApiService) handleTeams(c *gin.Context) {
-	c.JSON(200, "Not implemented")
-	return
+func (api *ApiService) handleTimeline(c *gin.Context) {
+	id := c.Query("id")
+	if id == "" {
+		c.JSON(400, gin.H{"error": api.message(c, "id is required")})
+		return
+	}
+	api.analysisService.mu.RLock()
+	defer api.analysisService.mu.RUnlock()
+	data, exists := api.analysisService.gamesData[id]
+	if !exists {
+		c.JSON(404, gin.H{"error": api.message(c, "game not found")})
+		return
+	}
+	isFinished := api.analysisService.endGameStatus[id]
+	if !api.publishRunningGame && !isFinished {
+		c.JSON(403, gin.H{"error": api.message(c, "game is running")})
+		return
+	}
+	reveal, ok := api.reveal(c, isFinished)
+	if !ok {
+		c.JSON(403, gin.H{"error": api.message(c, "reveal is not allowed")})
+		return
+	}
+	agents, timeline := data.agents, data.timeline
+	if !reveal {
+		agents, timeline = redactAgents(agents), redactTimeline(timeline)
+	}
+	c.JSON(200, gin.H{
+		"game_id":     id,
+		"is_finished": isFinished,
+		"revealed":    reveal,
+		"win_side":    data.winSide,
+		"agents":      agents,
+		"timeline":    timeline,
+	})
+}
 
-	resp := gin.H{}
+func (api *ApiService) handleTeams(c *gin.Context) {
+	api.analysisService.mu.RLock()
+	defer api.analysisService.mu.RUnlock()
+	games := make(map[string]int)
+	running := make(map[string]int)
+	for team, totals := range api.analysisService.teamTotals {
+		games[team] += totals.games
+	}
 	for id, data := range api.analysisService.gamesData {
 		if api.analysisService.endGameStatus[id] {
 			continue
 		}
-		teams := []string{}
-		for _, agent := range data.agents {
-			if agentMap, ok := agent.(map[string]interface{}); ok {
-				team := agentMap["team"].(string)
-				teams = append(teams, team)
+		for _, team := range data.teams() {
+			games[team]++
+			running[team]++
+		}
+	}
+	teams := make([]gin.H, 0, len(games))
+	for _, team := range slices.Sorted(maps.Keys(games)) {
+		teams = append(teams, gin.H{
+			"team":    team,
+			"games":   games[team],
+			"running": running[team],
+		})
+	}
+	c.JSON(200, gin.H{"teams": teams})
+}
+
+func (api *ApiService) handleTeam(c *gin.Context) {
+	team := c.Param("team")
+	revealRunning, ok := api.reveal(c, false)
+	if !ok {
+		c.JSON(403, gin.H{"error": api.message(c, "reveal is not allowed")})
+		return
+	}
+	api.analysisService.mu.RLock()
+	defer api.analysisService.mu.RUnlock()
+
+	// 成績とレスポンス時間は、メモリから削除された終了したゲームも含む通算の集計を使用する
+	games := make([]gin.H, 0)
+	stats := make(map[string]map[string]int)
+	recentErrors := make([]map[string]interface{}, 0)
+	var latencySum, latencyCount int64
+	totalGames := 0
+	totals, hasTotals := api.analysisService.teamTotals[team]
+	if hasTotals {
+		for role, values := range totals.stats {
+			stats[role] = maps.Clone(values)
+		}
+		latencySum, latencyCount = totals.latencySum, totals.latencyCount
+		totalGames = totals.games
+	}
+	for _, id := range slices.Sorted(maps.Keys(api.analysisService.gamesData)) {
+		data := api.analysisService.gamesData[id]
+		if !slices.Contains(data.teams(), team) {
+			continue
+		}
+		isFinished := api.analysisService.endGameStatus[id]
+		if !isFinished {
+			totalGames++
+		}
+		game := gin.H{
+			"game_id":     id,
+			"is_finished": isFinished,
+		}
+		if !isFinished && !api.publishRunningGame {
+			games = append(games, game)
+			continue
+		}
+
+		summary := data.summarizeTeam(team)
+		if !isFinished {
+			latencySum += summary.latencySum
+			latencyCount += summary.latencyCount
+		}
+		// リクエストのパケットには役職や占い結果が含まれるため、進行中のゲームは公開が許可された場合のみ返す
+		if !isFinished && !revealRunning {
+			for _, entry := range summary.errors {
+				delete(entry, "request")
 			}
 		}
-		for _, team := range teams {
-			if _, exists := resp[team]; !exists {
-				resp[team] = []string{}
+		recentErrors = append(recentErrors, summary.errors...)
+		agents := make([]gin.H, 0, len(summary.agentRoles))
+		for _, agent := range slices.Sorted(maps.Keys(summary.agentRoles)) {
+			if isFinished || revealRunning {
+				agents = append(agents, gin.H{"agent": agent, "role": summary.agentRoles[agent]})
+			} else {
+				agents = append(agents, gin.H{"agent": agent})
 			}
-			resp[team] = append(resp[team].([]string), id)
 		}
+		game["win_side"] = data.winSide
+		game["agents"] = agents
+		games = append(games, game)
 	}
-	c.JSON(200, resp)
+	if len(games) == 0 && !hasTotals {
+		c.JSON(404, gin.H{"error": api.message(c, "team not found")})
+		return
+	}
+
+	slices.SortFunc(recentErrors, func(a, b map[string]interface{}) int {
+		return int(b["timestamp"].(int64) - a["timestamp"].(int64))
+	})
+	if len(recentErrors) > recentErrorCount {
+		recentErrors = recentErrors[:recentErrorCount]
+	}
+	var averageLatency float64
+	if latencyCount > 0 {
+		averageLatency = float64(latencySum) / float64(latencyCount)
+	}
+	c.JSON(200, gin.H{
+		"team":               team,
+		"total_games":        totalGames,
+		"games":              games,
+		"stats":              stats,
+		"average_latency_ms": averageLatency,
+		"recent_errors":      recentErrors,
+	})
 }
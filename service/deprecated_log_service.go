@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/kano-lab/aiwolf-nlp-server/model"
@@ -14,6 +15,7 @@ type DeprecatedLogService struct {
 	deprecatedLogsData map[string]*DeprecatedLogData
 	outputDir          string
 	templateFilename   string
+	mu                 sync.Mutex
 }
 
 type DeprecatedLogData struct {
@@ -31,7 +33,51 @@ func NewDeprecatedLogService(config model.Config) *DeprecatedLogService {
 	}
 }
 
-func (d *DeprecatedLogService) TrackStartGame(id string, agents []*model.Agent) {
+func (d *DeprecatedLogService) HandleEvent(event model.Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch e := event.(type) {
+	case model.GameStartedEvent:
+		d.trackStartGame(e.GameID, e.Agents)
+	case model.DayStartedEvent:
+		for _, agent := range e.Agents {
+			d.appendLog(e.GameID, fmt.Sprintf("%d,status,%d,%s,%s,%s", e.Day, agent.Idx, agent.Role.Name, e.StatusMap[agent].String(), agent.Name))
+		}
+	case model.TalkSpokenEvent:
+		if e.Request == model.R_TALK {
+			d.appendLog(e.GameID, fmt.Sprintf("%d,talk,%d,%d,%d,%s", e.Day, e.Talk.Idx, e.Talk.Turn, e.Talk.Agent.Idx, e.Talk.Text))
+		} else {
+			d.appendLog(e.GameID, fmt.Sprintf("%d,whisper,%d,%d,%d,%s", e.Day, e.Talk.Idx, e.Talk.Turn, e.Talk.Agent.Idx, e.Talk.Text))
+		}
+	case model.VoteCastEvent:
+		switch e.Request {
+		case model.R_VOTE:
+			d.appendLog(e.GameID, fmt.Sprintf("%d,vote,%d,%d", e.Day, e.Vote.Agent.Idx, e.Vote.Target.Idx))
+		case model.R_ATTACK:
+			d.appendLog(e.GameID, fmt.Sprintf("%d,attackVote,%d,%d", e.Day, e.Vote.Agent.Idx, e.Vote.Target.Idx))
+		}
+	case model.ExecutedEvent:
+		d.appendLog(e.GameID, fmt.Sprintf("%d,execute,%d,%s", e.Day, e.Agent.Idx, e.Agent.Role.Name))
+	case model.DivinedEvent:
+		d.appendLog(e.GameID, fmt.Sprintf("%d,divine,%d,%d,%s", e.Day, e.Judge.Agent.Idx, e.Judge.Target.Idx, e.Judge.Result))
+	case model.GuardedEvent:
+		d.appendLog(e.GameID, fmt.Sprintf("%d,guard,%d,%d,%s", e.Day, e.Guard.Agent.Idx, e.Guard.Target.Idx, e.Guard.Target.Role.Name))
+	case model.AttackedEvent:
+		if e.Agent != nil {
+			d.appendLog(e.GameID, fmt.Sprintf("%d,attack,%d,%t", e.Day, e.Agent.Idx, e.Succeeded))
+		} else {
+			d.appendLog(e.GameID, fmt.Sprintf("%d,attack,-1,true", e.Day))
+		}
+	case model.GameEndedEvent:
+		for _, agent := range e.Agents {
+			d.appendLog(e.GameID, fmt.Sprintf("%d,status,%d,%s,%s,%s", e.Day, agent.Idx, agent.Role.Name, e.StatusMap[agent].String(), agent.Name))
+		}
+		d.appendLog(e.GameID, fmt.Sprintf("%d,result,%d,%d,%s", e.Day, e.Villagers, e.Werewolves, e.WinSide))
+		d.trackEndGame(e.GameID)
+	}
+}
+
+func (d *DeprecatedLogService) trackStartGame(id string, agents []model.Agent) {
 	deprecatedLogData := &DeprecatedLogData{
 		id:   id,
 		logs: make([]string, 0),
@@ -66,14 +112,14 @@ func (d *DeprecatedLogService) TrackStartGame(id string, agents []*model.Agent)
 	d.deprecatedLogsData[id] = deprecatedLogData
 }
 
-func (d *DeprecatedLogService) TrackEndGame(id string) {
+func (d *DeprecatedLogService) trackEndGame(id string) {
 	if _, exists := d.deprecatedLogsData[id]; exists {
 		d.saveDeprecatedLog(id)
 		delete(d.deprecatedLogsData, id)
 	}
 }
 
-func (d *DeprecatedLogService) AppendLog(id string, log string) {
+func (d *DeprecatedLogService) appendLog(id string, log string) {
 	if deprecatedLogData, exists := d.deprecatedLogsData[id]; exists {
 		deprecatedLogData.logs = append(deprecatedLogData.logs, log)
 		d.saveDeprecatedLog(id)
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+type EventSubscriber interface {
+	HandleEvent(event model.Event)
+}
+
+type EventBus struct {
+	subscribers []EventSubscriber
+	mu          sync.RWMutex
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make([]EventSubscriber, 0),
+	}
+}
+
+func (b *EventBus) Subscribe(subscriber EventSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, subscriber)
+}
+
+func (b *EventBus) Publish(event model.Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, subscriber := range b.subscribers {
+		subscriber.HandleEvent(event)
+	}
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+var defaultBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+type MetricsService struct {
+	path             string
+	buckets          []float64
+	activeGames      int
+	teamActiveGames  map[string]int
+	gameTeams        map[string][]string
+	latencies        map[string]*histogram
+	timeouts         map[[2]string]int
+	nameFallbacks    map[[2]string]int
+	errorAgents      map[string]int
+	gameOutcomes     map[string]int
+	errorAgentsMap   map[string]map[string]struct{}
+	waitingRoomSizes func() map[string]int
+	mu               sync.Mutex
+}
+
+type histogram struct {
+	counts []int
+	sum    float64
+	count  int
+}
+
+func NewMetricsService(config model.Config) *MetricsService {
+	path := config.MetricsService.Path
+	if path == "" {
+		path = "/metrics"
+	}
+	buckets := slices.Clone(config.MetricsService.Buckets)
+	if len(buckets) == 0 {
+		buckets = slices.Clone(defaultBuckets)
+	}
+	sort.Float64s(buckets)
+	return &MetricsService{
+		path:            path,
+		buckets:         buckets,
+		teamActiveGames: make(map[string]int),
+		gameTeams:       make(map[string][]string),
+		latencies:       make(map[string]*histogram),
+		timeouts:        make(map[[2]string]int),
+		nameFallbacks:   make(map[[2]string]int),
+		errorAgents:     make(map[string]int),
+		gameOutcomes:    make(map[string]int),
+		errorAgentsMap:  make(map[string]map[string]struct{}),
+	}
+}
+
+func (m *MetricsService) SetWaitingRoomSizes(waitingRoomSizes func() map[string]int) {
+	m.waitingRoomSizes = waitingRoomSizes
+}
+
+func (m *MetricsService) RegisterRoutes(router *gin.Engine) {
+	router.GET(m.path, m.handleMetrics)
+}
+
+func (m *MetricsService) HandleEvent(event model.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch e := event.(type) {
+	case model.GameStartedEvent:
+		m.activeGames++
+		teams := make([]string, 0)
+		for _, agent := range e.Agents {
+			if !slices.Contains(teams, agent.Team) {
+				teams = append(teams, agent.Team)
+				m.teamActiveGames[agent.Team]++
+			}
+		}
+		m.gameTeams[e.GameID] = teams
+		m.errorAgentsMap[e.GameID] = make(map[string]struct{})
+	case model.RequestEndedEvent:
+		m.observeRequest(e)
+	case model.GameEndedEvent:
+		m.activeGames--
+		for _, team := range m.gameTeams[e.GameID] {
+			m.teamActiveGames[team]--
+			if m.teamActiveGames[team] <= 0 {
+				delete(m.teamActiveGames, team)
+			}
+		}
+		delete(m.gameTeams, e.GameID)
+		m.gameOutcomes[string(e.WinSide)]++
+		delete(m.errorAgentsMap, e.GameID)
+	}
+}
+
+func (m *MetricsService) observeRequest(e model.RequestEndedEvent) {
+	h, exists := m.latencies[e.Request.Type]
+	if !exists {
+		h = &histogram{counts: make([]int, len(m.buckets))}
+		m.latencies[e.Request.Type] = h
+	}
+	seconds := e.Duration.Seconds()
+	for i, bucket := range m.buckets {
+		if seconds <= bucket {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+
+	key := [2]string{e.Request.Type, e.Agent.Team}
+	if errors.Is(e.Error, model.ErrResponseTimeout) || errors.Is(e.Error, model.ErrNameResponseTimeout) {
+		m.timeouts[key]++
+	}
+	if errors.Is(e.Error, model.ErrResponseTimeout) || errors.Is(e.Error, model.ErrNameResponseTimeout) || errors.Is(e.Error, model.ErrInvalidNameResponse) {
+		m.nameFallbacks[key]++
+	}
+	if e.Agent.HasError {
+		agents, exists := m.errorAgentsMap[e.GameID]
+		if !exists {
+			return
+		}
+		if _, exists := agents[e.Agent.Name]; !exists {
+			agents[e.Agent.Name] = struct{}{}
+			m.errorAgents[e.Agent.Team]++
+		}
+	}
+}
+
+func (m *MetricsService) handleMetrics(c *gin.Context) {
+	c.Data(200, "text/plain; version=0.0.4; charset=utf-8", []byte(m.Render()))
+}
+
+func (m *MetricsService) Render() string {
+	var waitingRoomSizes map[string]int
+	if m.waitingRoomSizes != nil {
+		waitingRoomSizes = m.waitingRoomSizes()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sb strings.Builder
+
+	writeHeader(&sb, "aiwolf_active_games", "gauge", "Number of games in progress.")
+	fmt.Fprintf(&sb, "aiwolf_active_games %d\n", m.activeGames)
+
+	writeHeader(&sb, "aiwolf_team_active_games", "gauge", "Number of games in progress per team.")
+	for _, team := range sortedKeys(m.teamActiveGames) {
+		fmt.Fprintf(&sb, "aiwolf_team_active_games{team=%s} %d\n", quote(team), m.teamActiveGames[team])
+	}
+
+	writeHeader(&sb, "aiwolf_waiting_room_connections", "gauge", "Number of connections in the waiting room per team.")
+	for _, team := range sortedKeys(waitingRoomSizes) {
+		fmt.Fprintf(&sb, "aiwolf_waiting_room_connections{team=%s} %d\n", quote(team), waitingRoomSizes[team])
+	}
+
+	writeHeader(&sb, "aiwolf_agent_response_seconds", "histogram", "Agent response latency per request type.")
+	for _, request := range sortedKeys(m.latencies) {
+		h := m.latencies[request]
+		for i, bucket := range m.buckets {
+			fmt.Fprintf(&sb, "aiwolf_agent_response_seconds_bucket{request=%s,le=%s} %d\n", quote(request), quote(strconv.FormatFloat(bucket, 'g', -1, 64)), h.counts[i])
+		}
+		fmt.Fprintf(&sb, "aiwolf_agent_response_seconds_bucket{request=%s,le=\"+Inf\"} %d\n", quote(request), h.count)
+		fmt.Fprintf(&sb, "aiwolf_agent_response_seconds_sum{request=%s} %s\n", quote(request), strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&sb, "aiwolf_agent_response_seconds_count{request=%s} %d\n", quote(request), h.count)
+	}
+
+	writeHeader(&sb, "aiwolf_agent_timeouts_total", "counter", "Number of agent response timeouts per request type and team.")
+	writeLabeledCounters(&sb, "aiwolf_agent_timeouts_total", m.timeouts)
+
+	writeHeader(&sb, "aiwolf_agent_name_fallbacks_total", "counter", "Number of NAME request fallbacks per request type and team.")
+	writeLabeledCounters(&sb, "aiwolf_agent_name_fallbacks_total", m.nameFallbacks)
+
+	writeHeader(&sb, "aiwolf_error_agents_total", "counter", "Number of agents that entered the error state per team.")
+	for _, team := range sortedKeys(m.errorAgents) {
+		fmt.Fprintf(&sb, "aiwolf_error_agents_total{team=%s} %d\n", quote(team), m.errorAgents[team])
+	}
+
+	writeHeader(&sb, "aiwolf_games_finished_total", "counter", "Number of finished games per winning side.")
+	for _, side := range sortedKeys(m.gameOutcomes) {
+		fmt.Fprintf(&sb, "aiwolf_games_finished_total{win_side=%s} %d\n", quote(side), m.gameOutcomes[side])
+	}
+	return sb.String()
+}
+
+func writeHeader(sb *strings.Builder, name string, kind string, help string) {
+	fmt.Fprintf(sb, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(sb, "# TYPE %s %s\n", name, kind)
+}
+
+func writeLabeledCounters(sb *strings.Builder, name string, counters map[[2]string]int) {
+	keys := make([][2]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, key := range keys {
+		fmt.Fprintf(sb, "%s{request=%s,team=%s} %d\n", name, quote(key[0]), quote(key[1]), counters[key])
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
@@ -0,0 +1,259 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kano-lab/aiwolf-nlp-server/core"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+func TestAdminService(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.Server.WebSocket.Port += 10
+	config.AdminService.Enable = true
+	config.AdminService.Token = "secret"
+	server, err := core.NewServer(*config)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	go server.Run()
+	time.Sleep(5 * time.Second)
+
+	host := config.Server.WebSocket.Host + ":" + strconv.Itoa(config.Server.WebSocket.Port)
+	request := func(method string, path string, token string) (int, map[string]interface{}) {
+		return adminRequest(t, method, "http://"+host+path, token, "")
+	}
+
+	if code, _ := request("GET", "/admin/games", ""); code != 401 {
+		t.Errorf("Expected 401 without token, got %d", code)
+	}
+	if code, _ := request("GET", "/admin/games", "wrong"); code != 401 {
+		t.Errorf("Expected 401 with wrong token, got %d", code)
+	}
+
+	u := url.URL{Scheme: "ws", Host: host, Path: "/ws"}
+	names := []string{"admina", "adminb", "adminc"}
+	clients := make(map[string]*DummyClient)
+	for _, name := range names {
+		client, err := NewDummyClient(u, name, t)
+		if err != nil {
+			t.Fatalf("Failed to create WebSocket client: %v", err)
+		}
+		clients[name] = client
+		defer client.Close()
+	}
+	time.Sleep(time.Second)
+
+	code, body := request("GET", "/admin/waiting_room", "secret")
+	if code != 200 || len(body["teams"].(map[string]interface{})) != len(names) {
+		t.Errorf("Unexpected waiting room: %d %v", code, body)
+	}
+	if code, _ := request("POST", "/admin/games", "secret"); code != 409 {
+		t.Errorf("Expected 409 for force start with insufficient connections, got %d", code)
+	}
+	if code, _ := request("DELETE", "/admin/waiting_room/admina/admina", "secret"); code != 200 {
+		t.Errorf("Expected 200 for kick, got %d", code)
+	}
+	select {
+	case <-clients["admina"].done:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Kicked connection was not closed")
+	}
+	code, body = request("GET", "/admin/waiting_room", "secret")
+	if code != 200 || len(body["teams"].(map[string]interface{})) != len(names)-1 {
+		t.Errorf("Unexpected waiting room after kick: %d %v", code, body)
+	}
+	if code, _ := request("DELETE", "/admin/waiting_room/admina/admina", "secret"); code != 404 {
+		t.Errorf("Expected 404 for kicking missing connection, got %d", code)
+	}
+	if code, _ := request("POST", "/admin/games/unknown/abort", "secret"); code != 404 {
+		t.Errorf("Expected 404 for aborting missing game, got %d", code)
+	}
+}
+
+func TestAdminStepMode(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.Server.WebSocket.Port += 11
+	config.AdminService.Enable = true
+	config.AdminService.Token = "secret"
+	config.Game.Debug.StepMode = true
+	server, err := core.NewServer(*config)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	go server.Run()
+	time.Sleep(5 * time.Second)
+
+	host := config.Server.WebSocket.Host + ":" + strconv.Itoa(config.Server.WebSocket.Port)
+	u := url.URL{Scheme: "ws", Host: host, Path: "/ws"}
+	clients := make([]*DummyClient, config.Game.AgentCount)
+	for i := range clients {
+		client, err := NewDummyClient(u, "step"+string(rune('a'+i)), t)
+		if err != nil {
+			t.Fatalf("Failed to create WebSocket client: %v", err)
+		}
+		clients[i] = client
+		defer client.Close()
+	}
+	time.Sleep(2 * time.Second)
+
+	_, body := adminRequest(t, "GET", "http://"+host+"/admin/games", "secret", "")
+	games := body["games"].([]interface{})
+	if len(games) != 1 {
+		t.Fatalf("Expected 1 game, got %v", body)
+	}
+	id := games[0].(map[string]interface{})["game_id"].(string)
+
+	_, status := adminRequest(t, "GET", "http://"+host+"/admin/games/"+id+"/status", "secret", "")
+	if status["phase"] != "TALK[0]" || status["is_paused"] != true || status["game_status"] == nil {
+		t.Fatalf("Expected game to be paused at the first talk turn, got %v", status)
+	}
+	if talks := status["game_status"].(map[string]interface{})["talks"].([]interface{}); len(talks) != 0 {
+		t.Errorf("Expected no talks before stepping, got %v", talks)
+	}
+
+	adminRequest(t, "POST", "http://"+host+"/admin/games/"+id+"/step", "secret", `{"count":1}`)
+	time.Sleep(time.Second)
+	_, status = adminRequest(t, "GET", "http://"+host+"/admin/games/"+id+"/status", "secret", "")
+	if talks := status["game_status"].(map[string]interface{})["talks"].([]interface{}); len(talks) != 1 {
+		t.Errorf("Expected 1 talk after stepping, got %v", talks)
+	}
+
+	adminRequest(t, "PUT", "http://"+host+"/admin/games/"+id+"/step_mode", "secret", `{"enable":false}`)
+	adminRequest(t, "POST", "http://"+host+"/admin/games/"+id+"/resume", "secret", "")
+	for _, client := range clients {
+		select {
+		case <-client.done:
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Timeout")
+		}
+	}
+}
+
+func TestAdminForceStartWithMatchOptimizer(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.Server.WebSocket.Port += 18
+	config.AdminService.Enable = true
+	config.AdminService.Token = "secret"
+	config.MatchOptimizer.Enable = true
+	config.MatchOptimizer.TeamCount = config.Game.AgentCount
+	config.MatchOptimizer.GameCount = 1
+	config.MatchOptimizer.OutputPath = filepath.Join(t.TempDir(), "match_optimizer.json")
+	server, err := core.NewServer(*config)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	go server.Run()
+	time.Sleep(5 * time.Second)
+
+	host := config.Server.WebSocket.Host + ":" + strconv.Itoa(config.Server.WebSocket.Port)
+	if code, _ := adminRequest(t, "POST", "http://"+host+"/admin/games", "secret", ""); code != 409 {
+		t.Errorf("Expected 409 for force start with match optimizer, got %d", code)
+	}
+}
+
+func TestAdminConfigReload(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.Server.WebSocket.Port += 12
+	config.AdminService.Enable = true
+	config.AdminService.Token = "secret"
+	server, err := core.NewServer(*config)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	go server.Run()
+	time.Sleep(5 * time.Second)
+
+	host := config.Server.WebSocket.Host + ":" + strconv.Itoa(config.Server.WebSocket.Port)
+	if code, _ := adminRequest(t, "POST", "http://"+host+"/admin/config/reload", "secret", ""); code != 422 {
+		t.Errorf("Expected 422 without config loader, got %d", code)
+	}
+
+	next := *config
+	server.SetConfigLoader(func() (*model.Config, error) {
+		reloaded := next
+		return &reloaded, nil
+	})
+	next.Server.WebSocket.Port++
+	code, body := adminRequest(t, "POST", "http://"+host+"/admin/config/reload", "secret", "")
+	if code != 422 || len(body["details"].([]interface{})) != 1 {
+		t.Errorf("Expected 422 for restart-only change, got %d %v", code, body)
+	}
+
+	next = *config
+	next.Game.Debug.StepMode = true
+	if code, body := adminRequest(t, "POST", "http://"+host+"/admin/config/reload", "secret", ""); code != 200 {
+		t.Fatalf("Expected 200 for reload, got %d %v", code, body)
+	}
+
+	// 接続やゲームの開始と並行して再読み込みを行っても、設定の読み書きが競合しないことを確認する
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(50 * time.Millisecond):
+				server.Reload()
+			}
+		}
+	}()
+
+	u := url.URL{Scheme: "ws", Host: host, Path: "/ws"}
+	for i := 0; i < config.Game.AgentCount; i++ {
+		client, err := NewDummyClient(u, "reload"+string(rune('a'+i)), t)
+		if err != nil {
+			t.Fatalf("Failed to create WebSocket client: %v", err)
+		}
+		defer client.Close()
+	}
+	time.Sleep(2 * time.Second)
+
+	_, body = adminRequest(t, "GET", "http://"+host+"/admin/games", "secret", "")
+	games := body["games"].([]interface{})
+	if len(games) != 1 || games[0].(map[string]interface{})["is_paused"] != true {
+		t.Fatalf("Expected new game to use reloaded step mode, got %v", body)
+	}
+	adminRequest(t, "POST", "http://"+host+"/admin/games/"+games[0].(map[string]interface{})["game_id"].(string)+"/abort", "secret", "")
+}
+
+func adminRequest(t *testing.T, method string, url string, token string, body string) (int, map[string]interface{}) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
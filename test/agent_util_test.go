@@ -0,0 +1,31 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/util"
+)
+
+func TestFindAgentByName(t *testing.T) {
+	agents := []*model.Agent{
+		{Idx: 1, Team: "alpha", Name: "alpha1"},
+		{Idx: 2, Team: "beta", Name: "beta1"},
+	}
+	for _, matchName := range []bool{false, true} {
+		if target := util.FindAgentByName(agents, "Agent[02]", matchName); target != agents[1] {
+			t.Errorf("Expected Agent[02], got %v", target)
+		}
+		if target := util.FindAgentByName(agents, "unknown", matchName); target != nil {
+			t.Errorf("Expected no agent, got %v", target)
+		}
+	}
+
+	// ペルソナを無効にした場合は、元の名前で指定された対象も受け付ける
+	if target := util.FindAgentByName(agents, "beta1", true); target != agents[1] {
+		t.Errorf("Expected name fallback to find beta1, got %v", target)
+	}
+	if target := util.FindAgentByName(agents, "beta1", false); target != nil {
+		t.Errorf("Expected name fallback to be disabled, got %v", target)
+	}
+}
@@ -0,0 +1,96 @@
+package test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kano-lab/aiwolf-nlp-server/core"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+func TestAnalyzerReport(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	dir := t.TempDir()
+	config.MatchOptimizer.OutputPath = filepath.Join(dir, "match_optimizer.json")
+	config.DeprecatedLogService.OutputDir = filepath.Join(dir, "deprecated")
+	config.AnalysisService.OutputDir = filepath.Join(dir, "analysis")
+	config.Analyzer.OutputDir = filepath.Join(dir, "report")
+	config.Analyzer.Html = true
+
+	files := map[string]string{
+		config.MatchOptimizer.OutputPath: `{
+			"idx_team_map": {"0": "alpha", "1": "beta"},
+			"role_num_map": {"SEER": 1, "WEREWOLF": 1},
+			"scheduled_matches": [{"role_idxs": {"SEER": [0], "WEREWOLF": [1]}, "weight": 0}, {"role_idxs": {"SEER": [1], "WEREWOLF": [0]}, "weight": 0}],
+			"ended_matches": [{"SEER": [0], "WEREWOLF": [1]}]
+		}`,
+		filepath.Join(config.DeprecatedLogService.OutputDir, "game.log"): "0,status,1,SEER,ALIVE,alpha1\n" +
+			"0,status,2,WEREWOLF,ALIVE,beta1\n" +
+			"1,status,1,SEER,ALIVE,alpha1\n" +
+			"1,status,2,WEREWOLF,,beta1\n" +
+			"1,result,1,0,VILLAGER\n",
+		filepath.Join(config.AnalysisService.OutputDir, "game.json"): `{
+			"agents": [{"agent": "Agent[01]", "team": "alpha", "role": "SEER"}, {"agent": "Agent[02]", "team": "beta", "role": "WEREWOLF"}],
+			"entries": [
+				{"agent": "Agent[01]", "request_timestamp": 1000, "response_timestamp": 1100},
+				{"agent": "Agent[01]", "request_timestamp": 2000, "response_timestamp": 2300, "error": "timeout"},
+				{"agent": "Agent[02]", "timestamp": 2500, "violations": []}
+			]
+		}`,
+	}
+	for path, content := range files {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write fixture: %v", err)
+		}
+	}
+
+	core.Analyzer(*config)
+
+	data, err := os.ReadFile(filepath.Join(config.Analyzer.OutputDir, "report.json"))
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	var report core.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Teams) != 2 || report.Teams[0].Team != "alpha" {
+		t.Fatalf("Unexpected teams: %v", report.Teams)
+	}
+	seer := report.Teams[0].Roles["SEER"]
+	if seer.Scheduled != 1 || seer.Ended != 1 || seer.Win != 1 || seer.Error != 0 {
+		t.Errorf("Unexpected seer report: %+v", seer)
+	}
+	if seer.Timing.Requests != 2 || seer.Timing.Errors != 1 || seer.Timing.MeanMs != 200 || seer.Timing.MaxMs != 300 {
+		t.Errorf("Unexpected seer timing: %+v", seer.Timing)
+	}
+	total := report.Teams[1].Total
+	if total.Scheduled != 2 || total.Ended != 1 || total.Lose != 1 || total.ErrorRate != 1 || total.Timing.Requests != 0 {
+		t.Errorf("Unexpected beta total: %+v", total)
+	}
+
+	file, err := os.Open(filepath.Join(config.Analyzer.OutputDir, "report.csv"))
+	if err != nil {
+		t.Fatalf("Failed to open csv report: %v", err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse csv report: %v", err)
+	}
+	// ヘッダ + alpha (SEER, WEREWOLF, ALL) + beta (SEER, WEREWOLF, ALL)
+	if len(records) != 7 || records[3][0] != "alpha" || records[3][1] != "ALL" {
+		t.Errorf("Unexpected csv report: %v", records)
+	}
+
+	if _, err := os.Stat(filepath.Join(config.Analyzer.OutputDir, "report.html")); err != nil {
+		t.Errorf("Expected html report: %v", err)
+	}
+}
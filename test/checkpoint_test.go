@@ -0,0 +1,110 @@
+package test
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kano-lab/aiwolf-nlp-server/core"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	newServer := func(portOffset int, outputDir string, resumeTimeout time.Duration, stepMode bool) string {
+		config, err := model.LoadFromPath("../config/debug.yml")
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		config.Server.WebSocket.Port += portOffset
+		config.AdminService.Enable = true
+		config.AdminService.Token = "secret"
+		config.Game.Debug.StepMode = stepMode
+		config.Checkpoint.Enable = true
+		config.Checkpoint.OutputDir = outputDir
+		config.Checkpoint.ResumeTimeout = resumeTimeout
+		server, err := core.NewServer(*config)
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		go server.Run()
+		time.Sleep(5 * time.Second)
+		return config.Server.WebSocket.Host + ":" + strconv.Itoa(config.Server.WebSocket.Port)
+	}
+	connect := func(host string) []*DummyClient {
+		u := url.URL{Scheme: "ws", Host: host, Path: "/ws"}
+		clients := make([]*DummyClient, 5)
+		for i := range clients {
+			client, err := NewDummyClient(u, "checkpoint"+string(rune('a'+i)), t)
+			if err != nil {
+				t.Fatalf("Failed to create WebSocket client: %v", err)
+			}
+			clients[i] = client
+		}
+		time.Sleep(2 * time.Second)
+		return clients
+	}
+	waitClients := func(clients []*DummyClient) {
+		for _, client := range clients {
+			select {
+			case <-client.done:
+			case <-time.After(30 * time.Second):
+				t.Fatalf("Timeout")
+			}
+		}
+	}
+
+	// ゲームを一時停止した状態でチェックポイントを複製し、クラッシュした場合を再現する
+	host := newServer(13, filepath.Join(dir, "crashed"), time.Minute, true)
+	clients := connect(host)
+	_, body := adminRequest(t, "GET", "http://"+host+"/admin/games", "secret", "")
+	id := body["games"].([]interface{})[0].(map[string]interface{})["game_id"].(string)
+	data, err := os.ReadFile(filepath.Join(dir, "crashed", id+".json"))
+	if err != nil {
+		t.Fatalf("Failed to read checkpoint: %v", err)
+	}
+	var saved map[string]interface{}
+	json.Unmarshal(data, &saved)
+	if saved["day"] != float64(0) || saved["phase"] != "day" {
+		t.Errorf("Expected checkpoint at the day phase of day 0, got %v %v", saved["day"], saved["phase"])
+	}
+	os.MkdirAll(filepath.Join(dir, "aborted"), 0755)
+	os.WriteFile(filepath.Join(dir, "aborted", id+".json"), data, 0644)
+	// 途中のフェーズから再開できることを確認するため、占いの前に保存したチェックポイントとして再開する
+	saved["phase"] = "divine"
+	resumed, _ := json.Marshal(saved)
+	os.MkdirAll(filepath.Join(dir, "resumed"), 0755)
+	os.WriteFile(filepath.Join(dir, "resumed", id+".json"), resumed, 0644)
+	adminRequest(t, "POST", "http://"+host+"/admin/games/"+id+"/abort", "secret", "")
+	waitClients(clients)
+	if _, err := os.Stat(filepath.Join(dir, "crashed", id+".json")); !os.IsNotExist(err) {
+		t.Errorf("Expected checkpoint to be removed after the game ended: %v", err)
+	}
+
+	host = newServer(14, filepath.Join(dir, "resumed"), time.Minute, false)
+	clients = connect(host)
+	_, body = adminRequest(t, "GET", "http://"+host+"/admin/games", "secret", "")
+	games := body["games"].([]interface{})
+	if len(games) != 1 || games[0].(map[string]interface{})["game_id"] != id {
+		t.Fatalf("Expected game %s to be resumed, got %v", id, body)
+	}
+	waitClients(clients)
+	if _, err := os.Stat(filepath.Join(dir, "resumed", id+".json")); !os.IsNotExist(err) {
+		t.Errorf("Expected checkpoint to be removed after the resumed game ended: %v", err)
+	}
+
+	newServer(15, filepath.Join(dir, "aborted"), time.Second, false)
+	data, err = os.ReadFile(filepath.Join(dir, "aborted", id+".json"))
+	if err != nil {
+		t.Fatalf("Failed to read checkpoint: %v", err)
+	}
+	var checkpoint map[string]interface{}
+	json.Unmarshal(data, &checkpoint)
+	if checkpoint["abort_reason"] == nil {
+		t.Errorf("Expected checkpoint to be aborted after the resume timeout, got %v", checkpoint)
+	}
+}
@@ -0,0 +1,215 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+func TestConfigValidation(t *testing.T) {
+	for _, path := range []string{"../config/default.yml", "../config/debug.yml", "../config/infinite_debug.yml"} {
+		if _, err := model.LoadFromPath(path); err != nil {
+			t.Errorf("Failed to load %s: %v", path, err)
+		}
+	}
+
+	data, err := os.ReadFile("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	content := strings.Replace(string(data), "  self_match: false", "  self_match: false\n  selfmatch: true", 1)
+	content = strings.Replace(content, "    max_count: 1 # 1位タイの場合の最大再投票回数", "    max_count: 0", 1)
+	content = strings.Replace(content, "    action: 60s # エージェントのアクションのタイムアウト時間", "    action: -1s", 1)
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	_, err = model.LoadFromPath(path)
+	if err == nil {
+		t.Fatalf("Expected validation error")
+	}
+	for _, field := range []string{"server.selfmatch", "game.vote.max_count", "game.timeout.action"} {
+		if !strings.Contains(err.Error(), field+":") {
+			t.Errorf("Expected error for %s, got %v", field, err)
+		}
+	}
+
+	config, _ := model.LoadFromPath("../config/debug.yml")
+	config.AdminService.Enable = true
+	config.MatchOptimizer.Enable = true
+	config.MatchOptimizer.TeamCount = config.Game.AgentCount - 1
+	config.HumanClient.Enable = true
+	config.HumanClient.Teams = nil
+	err = config.Validate()
+	var configErr model.ConfigError
+	if !errors.As(err, &configErr) || !strings.Contains(err.Error(), "admin_service.token") || !strings.Contains(err.Error(), "match_optimizer.team_count") || !strings.Contains(err.Error(), "human_client.teams") {
+		t.Errorf("Unexpected validation error: %v", err)
+	}
+}
+
+func TestConfigOverrides(t *testing.T) {
+	overrides, err := model.EnvOverrides([]string{
+		"PATH=/usr/bin",
+		"AIWOLF_SERVER_WEB_SOCKET_PORT=9090",
+		"AIWOLF_GAME_TALK_MAX_COUNT_PER_AGENT=3",
+		"AIWOLF_METRICS_SERVICE_BUCKETS=[1, 2]",
+	})
+	if err != nil {
+		t.Fatalf("Failed to read env overrides: %v", err)
+	}
+	set, err := model.ParseOverride("server.web_socket.port=9191", "--set")
+	if err != nil {
+		t.Fatalf("Failed to parse override: %v", err)
+	}
+	overrides = append(overrides, set)
+	timeout, _ := model.ParseOverride("game.timeout.action=30s", "--set")
+	overrides = append(overrides, timeout)
+	revealToken, _ := model.ParseOverride("api_service.reveal_token=secret", "--set")
+	overrides = append(overrides, revealToken)
+
+	config, err := model.LoadFromPath("../config/debug.yml", overrides...)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Server.WebSocket.Port != 9191 {
+		t.Errorf("Expected --set to take precedence over env, got %d", config.Server.WebSocket.Port)
+	}
+	if config.Game.Talk.MaxCount.PerAgent != 3 || config.Game.Whisper.MaxCount.PerAgent != 5 {
+		t.Errorf("Unexpected talk max count: %d %d", config.Game.Talk.MaxCount.PerAgent, config.Game.Whisper.MaxCount.PerAgent)
+	}
+	if len(config.MetricsService.Buckets) != 2 || config.Game.Timeout.Action.Seconds() != 30 {
+		t.Errorf("Unexpected overrides: %v %v", config.MetricsService.Buckets, config.Game.Timeout.Action)
+	}
+	if !strings.Contains(config.Dump(), "port: 9191") {
+		t.Errorf("Dump does not contain resolved port:\n%s", config.Dump())
+	}
+	if config.ApiService.RevealToken != "secret" || strings.Contains(config.Dump(), "secret") {
+		t.Errorf("Expected reveal token to be masked:\n%s", config.Dump())
+	}
+
+	if _, err := model.EnvOverrides([]string{"AIWOLF_SERVER_PROT=1"}); err == nil {
+		t.Errorf("Expected error for unknown env")
+	}
+	if _, err := model.ParseOverride("server.web_socket.port", "--set"); err == nil {
+		t.Errorf("Expected error for override without value")
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	data, err := os.ReadFile("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "  language:") || strings.HasPrefix(line, "  shutdown:") || strings.HasPrefix(line, "    timeout: 1m") || strings.HasPrefix(line, "    progress_interval:") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config, err := model.LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("Expected config without language and shutdown keys to load: %v", err)
+	}
+	if config.Server.Language != "ja" || config.Game.Language != "ja" || config.Server.Shutdown.Timeout != 30*time.Minute || config.Server.Shutdown.ProgressInterval != 15*time.Second {
+		t.Errorf("Unexpected defaults: %v %v %v", config.Server.Language, config.Game.Language, config.Server.Shutdown)
+	}
+
+	content := strings.Replace(string(data), `  language: "ja" # サーバログの言語 (ja, en)`, `  language: "fr"`, 1)
+	content = strings.Replace(content, "    progress_interval: 15s", "    progress_interval: 0s", 1)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	_, err = model.LoadFromPath(path)
+	for _, field := range []string{"server.language", "server.shutdown.progress_interval"} {
+		if err == nil || !strings.Contains(err.Error(), field+":") {
+			t.Errorf("Expected error for %s, got %v", field, err)
+		}
+	}
+}
+
+func TestAttackTieBreakDefault(t *testing.T) {
+	for _, path := range []string{"../config/default.yml", "../config/debug.yml", "../config/infinite_debug.yml"} {
+		config, err := model.LoadFromPath(path)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", path, err)
+		}
+		if config.Game.Attack.TieBreak != "" {
+			t.Errorf("Expected game.attack.tie_break to be unset in %s, got %s", path, config.Game.Attack.TieBreak)
+		}
+		for _, allowNoTarget := range []bool{false, true} {
+			config.Game.Attack.AllowNoTarget = allowNoTarget
+			settings, err := model.NewSettings(*config)
+			if err != nil {
+				t.Fatalf("Failed to create settings: %v", err)
+			}
+			expected := model.TB_RANDOM
+			if allowNoTarget {
+				expected = model.TB_NONE
+			}
+			if settings.AttackTieBreak != expected {
+				t.Errorf("Expected %s for allow_no_target=%v in %s, got %s", expected, allowNoTarget, path, settings.AttackTieBreak)
+			}
+		}
+	}
+}
+
+func TestPerRequestTimeoutAndFallback(t *testing.T) {
+	data, err := os.ReadFile("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	content := strings.Replace(string(data), "    # per_request:", "    per_request:", 1)
+	content = strings.Replace(content, "    #   talk: 120s", "      talk: 120s", 1)
+	content = strings.Replace(content, "    #   vote: 30s", "      vote: 30s", 1)
+	content = strings.Replace(content, `    vote: "abstain"`, `    vote: "exclude_self"`, 1)
+	content = strings.Replace(content, `    guard: "abstain"`, `    guard: "last"`, 1)
+	content = strings.Replace(content, `timeout_fallback: "abstain"`, `timeout_fallback: "random"`, 1)
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config, err := model.LoadFromPath(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	settings, err := model.NewSettings(*config)
+	if err != nil {
+		t.Fatalf("Failed to create settings: %v", err)
+	}
+	if settings.ActionTimeoutMap["TALK"] != 120000 || settings.ActionTimeoutFor(model.R_VOTE).Seconds() != 30 || settings.ActionTimeoutFor(model.R_DIVINE).Seconds() != 60 {
+		t.Errorf("Unexpected action timeouts: %v", settings.ActionTimeoutMap)
+	}
+	if settings.FallbackMap["VOTE"] != model.FB_EXCLUDE_SELF || settings.FallbackMap["GUARD"] != model.FB_LAST || settings.FallbackMap["ATTACK"] != model.FB_ABSTAIN {
+		t.Errorf("Unexpected fallback map: %v", settings.FallbackMap)
+	}
+	if settings.VoteTimeoutFallback != model.VF_RANDOM {
+		t.Errorf("Unexpected vote timeout fallback: %s", settings.VoteTimeoutFallback)
+	}
+	config.Game.Vote.TimeoutFallback = ""
+	if settings, _ := model.NewSettings(*config); settings.VoteTimeoutFallback != model.VF_EXCLUDE_SELF {
+		t.Errorf("Expected vote timeout fallback to follow fallback.vote, got %s", settings.VoteTimeoutFallback)
+	}
+
+	config.Game.Timeout.PerRequest["name"] = time.Second
+	config.Game.Timeout.PerRequest["attack"] = 0
+	config.Game.Fallback["talk"] = "abstain"
+	config.Game.Fallback["divine"] = "skip"
+	config.Game.Vote.TimeoutFallback = "skip"
+	err = config.Validate()
+	for _, field := range []string{"game.timeout.per_request.name", "game.timeout.per_request.attack", "game.fallback.talk", "game.fallback.divine", "game.vote.timeout_fallback"} {
+		if err == nil || !strings.Contains(err.Error(), field+":") {
+			t.Errorf("Expected error for %s, got %v", field, err)
+		}
+	}
+}
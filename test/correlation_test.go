@@ -0,0 +1,147 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+func TestResponseCorrelation(t *testing.T) {
+	agents := make(chan *model.Agent, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Failed to upgrade: %v", err)
+			return
+		}
+		conn, err := model.NewConnection(ws)
+		if err != nil {
+			t.Errorf("Failed to create connection: %v", err)
+			return
+		}
+		agent, _ := model.NewAgent(1, model.R_VILLAGER, *conn)
+		agents <- agent
+	}))
+	defer server.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer client.Close()
+	client.ReadMessage()
+	client.WriteMessage(websocket.TextMessage, []byte("correlation"))
+	agent := <-agents
+
+	requestIDs := make(chan int, 8)
+	gameIDs := make(chan string, 8)
+	go func() {
+		for i := 0; ; i++ {
+			_, message, err := client.ReadMessage()
+			if err != nil {
+				return
+			}
+			var packet struct {
+				GameID    string `json:"gameId"`
+				RequestID int    `json:"requestId"`
+				Request   string `json:"request"`
+			}
+			json.Unmarshal(message, &packet)
+			requestIDs <- packet.RequestID
+			gameIDs <- packet.GameID
+			switch i {
+			case 0:
+				client.WriteMessage(websocket.TextMessage, []byte("Agent[01]"))
+			case 1:
+				// タイムアウトした後に遅れてレスポンスを返す
+				time.Sleep(500 * time.Millisecond)
+				client.WriteMessage(websocket.TextMessage, []byte("Agent[02]"))
+			case 2:
+				client.WriteMessage(websocket.TextMessage, []byte("correlation"))
+			case 3:
+				client.WriteMessage(websocket.TextMessage, []byte("Agent[03]"))
+			case 4:
+				// リクエストIDを返すエージェントのレスポンスは、リクエストIDが一致するもののみ受け付ける
+				client.WriteMessage(websocket.TextMessage, []byte(`{"requestId":4,"response":"Agent[04]"}`))
+				client.WriteMessage(websocket.TextMessage, []byte(`{"requestId":5,"response":"Agent[05]"}`))
+			}
+		}
+	}()
+
+	// 対応するリクエストのないメッセージは次のリクエストのレスポンスとして扱われない
+	client.WriteMessage(websocket.TextMessage, []byte("unsolicited"))
+	time.Sleep(100 * time.Millisecond)
+	packet := model.Packet{GameID: "game", Request: &model.R_VOTE}
+	if res, err := agent.SendPacket(packet, 100*time.Millisecond, time.Second, time.Second); err != nil || res != "Agent[01]" {
+		t.Errorf("Expected Agent[01], got %q %v", res, err)
+	}
+	if _, err := agent.SendPacket(packet, 0, 2*time.Second, 200*time.Millisecond); err != model.ErrResponseTimeout {
+		t.Errorf("Expected response timeout, got %v", err)
+	}
+	if res, err := agent.SendPacket(packet, 100*time.Millisecond, time.Second, time.Second); err != nil || res != "Agent[03]" {
+		t.Errorf("Expected Agent[03] after the late response was discarded, got %q %v", res, err)
+	}
+	if res, err := agent.SendPacket(packet, 100*time.Millisecond, time.Second, time.Second); err != nil || res != "Agent[05]" {
+		t.Errorf("Expected Agent[05] for the echoed request ID, got %q %v", res, err)
+	}
+	if agent.HasError {
+		t.Errorf("Expected agent not to have an error")
+	}
+	for i := 1; i <= 5; i++ {
+		if id := <-requestIDs; id != i {
+			t.Errorf("Expected request ID %d, got %d", i, id)
+		}
+		if id := <-gameIDs; id != "game" {
+			t.Errorf("Expected game ID in request %d, got %q", i, id)
+		}
+	}
+}
+
+func TestRequestCancel(t *testing.T) {
+	agents := make(chan *model.Agent, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Failed to upgrade: %v", err)
+			return
+		}
+		conn, err := model.NewConnection(ws)
+		if err != nil {
+			t.Errorf("Failed to create connection: %v", err)
+			return
+		}
+		agent, _ := model.NewAgent(1, model.R_VILLAGER, *conn)
+		agents <- agent
+	}))
+	defer server.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer client.Close()
+	client.ReadMessage()
+	client.WriteMessage(websocket.TextMessage, []byte("cancel"))
+	agent := <-agents
+
+	// レスポンスを返さないエージェントへのリクエストは、取り消されるとタイムアウトを待たずに終了する
+	time.AfterFunc(200*time.Millisecond, agent.Receiver.Cancel)
+	start := time.Now()
+	if _, err := agent.SendPacket(model.Packet{Request: &model.R_VOTE}, time.Minute, time.Minute, time.Minute); err != model.ErrRequestCanceled {
+		t.Errorf("Expected request to be canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected canceled request to return promptly, took %v", elapsed)
+	}
+	if _, err := agent.SendPacket(model.Packet{Request: &model.R_FINISH}, time.Minute, time.Minute, time.Minute); err != nil {
+		t.Errorf("Expected requests without responses to be sent after cancel, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kano-lab/aiwolf-nlp-server/core"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+func TestDrain(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.Server.WebSocket.Port += 16
+	config.Server.Shutdown.Timeout = 3 * time.Second
+	config.Server.Shutdown.ProgressInterval = time.Second
+	config.AdminService.Enable = true
+	config.AdminService.Token = "secret"
+	config.Game.Debug.StepMode = true
+	server, err := core.NewServer(*config)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		server.Run()
+		close(stopped)
+	}()
+	time.Sleep(5 * time.Second)
+
+	host := config.Server.WebSocket.Host + ":" + strconv.Itoa(config.Server.WebSocket.Port)
+	u := url.URL{Scheme: "ws", Host: host, Path: "/ws"}
+	clients := make([]*DummyClient, config.Game.AgentCount+1)
+	for i := range clients {
+		client, err := NewDummyClient(u, "drain"+string(rune('a'+i)), t)
+		if err != nil {
+			t.Fatalf("Failed to create WebSocket client: %v", err)
+		}
+		clients[i] = client
+		defer client.Close()
+		time.Sleep(100 * time.Millisecond)
+	}
+	time.Sleep(time.Second)
+
+	if _, body := adminRequest(t, "GET", "http://"+host+"/admin/drain", "secret", ""); body["draining"] != false {
+		t.Errorf("Expected server not to be draining, got %v", body)
+	}
+	code, body := adminRequest(t, "POST", "http://"+host+"/admin/drain", "secret", "")
+	if code != 202 || body["draining"] != true || len(body["games"].([]interface{})) != 1 {
+		t.Fatalf("Expected drain to start with 1 running game, got %d %v", code, body)
+	}
+
+	select {
+	case <-clients[len(clients)-1].done:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Waiting connection was not closed")
+	}
+	if _, _, err := websocket.DefaultDialer.Dial(u.String(), nil); err == nil {
+		t.Errorf("Expected new connection to be rejected while draining")
+	}
+	if code, _ := adminRequest(t, "POST", "http://"+host+"/admin/games", "secret", ""); code != 503 {
+		t.Errorf("Expected 503 for force start while draining, got %d", code)
+	}
+
+	for _, client := range clients[:len(clients)-1] {
+		select {
+		case <-client.done:
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Timeout")
+		}
+	}
+	select {
+	case <-stopped:
+	case <-time.After(30 * time.Second):
+		t.Fatalf("Server did not stop after draining")
+	}
+}
@@ -189,7 +189,7 @@ func (dc *DummyClient) handleRequest(request model.Request, recv map[string]inte
 		return dc.handleInitialize(recv)
 	case model.R_TALK, model.R_WHISPER:
 		return dc.handleCommunication(recv)
-	case model.R_VOTE, model.R_DIVINE, model.R_GUARD, model.R_ATTACK:
+	case model.R_VOTE, model.R_DECLARE_VOTE, model.R_DIVINE, model.R_GUARD, model.R_ATTACK:
 		return dc.handleTarget(recv)
 	case model.R_DAILY_FINISH:
 		dc.talkIndex = 0
@@ -13,17 +13,19 @@ import (
 )
 
 func TestGame(t *testing.T) {
-	config, err := model.LoadFromPath("../config/debug.yml")
+	overrides, err := model.EnvOverrides(os.Environ())
+	if err != nil {
+		t.Fatalf("Failed to read overrides: %v", err)
+	}
+	config, err := model.LoadFromPath("../config/debug.yml", overrides...)
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
-	if _, exists := os.LookupEnv("GITHUB_ACTIONS"); exists {
-		config.Server.WebSocket.Host = model.WebSocketExternalHost
+	server, err := core.NewServer(*config)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
 	}
-	go func() {
-		server := core.NewServer(*config)
-		server.Run()
-	}()
+	go server.Run()
 	time.Sleep(5 * time.Second)
 
 	u := url.URL{Scheme: "ws", Host: config.Server.WebSocket.Host + ":" + strconv.Itoa(config.Server.WebSocket.Port), Path: "/ws"}
@@ -63,18 +65,22 @@ func TestGame(t *testing.T) {
 }
 
 func TestInfiniteGame(t *testing.T) {
-	config, err := model.LoadFromPath("../config/infinite_debug.yml")
+	if _, exists := os.LookupEnv("GITHUB_ACTIONS"); exists {
+		return
+	}
+	overrides, err := model.EnvOverrides(os.Environ())
+	if err != nil {
+		t.Fatalf("Failed to read overrides: %v", err)
+	}
+	config, err := model.LoadFromPath("../config/infinite_debug.yml", overrides...)
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
-	if _, exists := os.LookupEnv("GITHUB_ACTIONS"); exists {
-		config.Server.WebSocket.Host = model.WebSocketExternalHost
-		return
+	server, err := core.NewServer(*config)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
 	}
-	go func() {
-		server := core.NewServer(*config)
-		server.Run()
-	}()
+	go server.Run()
 	time.Sleep(5 * time.Second)
 
 	u := url.URL{Scheme: "ws", Host: config.Server.WebSocket.Host + ":" + strconv.Itoa(config.Server.WebSocket.Port), Path: "/ws"}
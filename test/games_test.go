@@ -0,0 +1,97 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/service"
+)
+
+func TestGameListing(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.AnalysisService.OutputDir = t.TempDir()
+	config.AnalysisService.Retention.MaxGames = 2
+
+	analysisService := service.NewAnalysisService(*config)
+	apiService := service.NewApiService(analysisService, *config)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	apiService.RegisterRoutes(router)
+
+	get := func(path string) (int, map[string]interface{}) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		var body map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &body)
+		return recorder.Code, body
+	}
+	ids := func(body map[string]interface{}) []string {
+		result := make([]string, 0)
+		for _, id := range body["games"].([]interface{}) {
+			result = append(result, id.(string))
+		}
+		return result
+	}
+
+	alpha := model.Agent{Idx: 1, Team: "alpha", Name: "alpha1", Role: model.R_SEER}
+	beta := model.Agent{Idx: 2, Team: "beta", Name: "beta1", Role: model.R_WEREWOLF}
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	games := map[string][]model.Agent{
+		"game1": {alpha, beta},
+		"game2": {alpha},
+		"game3": {beta},
+		"game4": {alpha, beta},
+	}
+	for i, id := range []string{"game1", "game2", "game3", "game4"} {
+		header := model.EventHeader{GameID: id, Timestamp: base.Add(time.Duration(i) * time.Hour)}
+		analysisService.HandleEvent(model.GameStartedEvent{EventHeader: header, Agents: games[id]})
+	}
+	analysisService.HandleEvent(model.GameEndedEvent{EventHeader: model.EventHeader{GameID: "game2"}, WinSide: model.T_VILLAGER})
+
+	_, body := get("/api/games")
+	if got := ids(body); len(got) != 4 || got[0] != "game4" || got[3] != "game1" {
+		t.Errorf("Unexpected games: %v", got)
+	}
+	_, body = get("/api/games?team=alpha&order=asc")
+	if got := ids(body); len(got) != 3 || got[0] != "game1" || got[2] != "game4" {
+		t.Errorf("Unexpected games for team: %v", got)
+	}
+	_, body = get("/api/games?status=finished&win_side=VILLAGER")
+	if got := ids(body); len(got) != 1 || got[0] != "game2" {
+		t.Errorf("Unexpected finished games: %v", got)
+	}
+	_, body = get("/api/games?since=2025-01-01T01:00:00Z&until=2025-01-01T03:00:00Z")
+	if got := ids(body); len(got) != 2 || got[0] != "game3" || got[1] != "game2" {
+		t.Errorf("Unexpected games in range: %v", got)
+	}
+
+	_, body = get("/api/games?order=asc&limit=3")
+	if got := ids(body); len(got) != 3 || body["next_cursor"] == nil {
+		t.Fatalf("Unexpected first page: %v", body)
+	}
+	_, body = get("/api/games?order=asc&limit=3&cursor=" + body["next_cursor"].(string))
+	if got := ids(body); len(got) != 1 || got[0] != "game4" || body["next_cursor"] != nil {
+		t.Errorf("Unexpected second page: %v", body)
+	}
+
+	for _, path := range []string{"/api/games?status=unknown", "/api/games?limit=0", "/api/games?cursor=invalid", "/api/games?since=yesterday"} {
+		if code, _ := get(path); code != 400 {
+			t.Errorf("Expected 400 for %s, got %d", path, code)
+		}
+	}
+
+	for _, id := range []string{"game1", "game3", "game4"} {
+		analysisService.HandleEvent(model.GameEndedEvent{EventHeader: model.EventHeader{GameID: id}, WinSide: model.T_WEREWOLF})
+	}
+	_, body = get("/api/games?order=asc")
+	if got := ids(body); len(got) != 2 || got[0] != "game3" || got[1] != "game4" {
+		t.Errorf("Expected oldest finished games to be evicted, got %v", got)
+	}
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kano-lab/aiwolf-nlp-server/core"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+func TestHeartbeat(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.Server.WebSocket.Port += 17
+	config.Server.Heartbeat.Enable = true
+	config.Server.Heartbeat.Interval = 500 * time.Millisecond
+	config.Server.Heartbeat.Timeout = 500 * time.Millisecond
+	config.AdminService.Enable = true
+	config.AdminService.Token = "secret"
+	server, err := core.NewServer(*config)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	go server.Run()
+	time.Sleep(5 * time.Second)
+
+	host := config.Server.WebSocket.Host + ":" + strconv.Itoa(config.Server.WebSocket.Port)
+	u := url.URL{Scheme: "ws", Host: host, Path: "/ws"}
+
+	// NAMEリクエストに応答した後に読み込みを止め、Pongを返さない接続
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("Failed to read NAME request: %v", err)
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte("heartbeatz"))
+	time.Sleep(500 * time.Millisecond)
+	if _, body := adminRequest(t, "GET", "http://"+host+"/admin/waiting_room", "secret", ""); len(body["teams"].(map[string]interface{})) != 1 {
+		t.Fatalf("Expected silent connection to be waiting, got %v", body)
+	}
+	time.Sleep(3 * time.Second)
+	if _, body := adminRequest(t, "GET", "http://"+host+"/admin/waiting_room", "secret", ""); len(body["teams"].(map[string]interface{})) != 0 {
+		t.Fatalf("Expected silent connection to be pruned, got %v", body)
+	}
+
+	clients := make([]*DummyClient, config.Game.AgentCount)
+	for i := range clients {
+		client, err := NewDummyClient(u, "heartbeat"+string(rune('a'+i)), t)
+		if err != nil {
+			t.Fatalf("Failed to create WebSocket client: %v", err)
+		}
+		clients[i] = client
+		defer client.Close()
+	}
+	for _, client := range clients {
+		select {
+		case <-client.done:
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Timeout")
+		}
+	}
+}
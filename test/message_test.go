@@ -0,0 +1,106 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/util"
+)
+
+func TestMessageCatalog(t *testing.T) {
+	fset := token.NewFileSet()
+	err := filepath.Walk("..", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == "test" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			selector, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			pkg, ok := selector.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			if !(pkg.Name == "slog" && (selector.Sel.Name == "Info" || selector.Sel.Name == "Warn" || selector.Sel.Name == "Error")) &&
+				!(pkg.Name == "errors" && selector.Sel.Name == "New") {
+				return true
+			}
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			message, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return true
+			}
+			if !util.HasMessage(message) {
+				t.Errorf("%s: message is not in catalog: %s", fset.Position(lit.Pos()), message)
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk source files: %v", err)
+	}
+}
+
+func TestTranslateMessage(t *testing.T) {
+	if util.TranslateMessage("ゲームを開始します", model.L_EN) != "Starting game" {
+		t.Errorf("Failed to translate message to English")
+	}
+	if util.TranslateMessage("game not found", model.L_JA) != "ゲームが見つかりません" {
+		t.Errorf("Failed to translate message to Japanese")
+	}
+	if util.TranslateMessage("unknown message", model.L_EN) != "unknown message" {
+		t.Errorf("Unknown message should be returned as is")
+	}
+}
+
+func TestMessageHandlerAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(util.NewMessageHandler(slog.NewJSONHandler(&buf, nil), model.L_EN))
+	logger.Info("ゲームを開始します", "text", "ゲームが見つかりません", "error", errors.New("ゲームが見つかりません"))
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to parse log: %v", err)
+	}
+	if record["msg"] != "Starting game" {
+		t.Errorf("Expected message to be translated, got %v", record["msg"])
+	}
+	if record["error"] != "game not found" {
+		t.Errorf("Expected error to be translated, got %v", record["error"])
+	}
+	if record["text"] != "ゲームが見つかりません" {
+		t.Errorf("Expected user content to be kept as is, got %v", record["text"])
+	}
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/service"
+)
+
+func TestMetricsService(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.MetricsService.Buckets = []float64{1, 5}
+
+	metrics := service.NewMetricsService(*config)
+	metrics.SetWaitingRoomSizes(func() map[string]int {
+		return map[string]int{"team-a": 2}
+	})
+
+	header := model.EventHeader{GameID: "game", Timestamp: time.Now()}
+	agent := model.Agent{Idx: 1, Team: "team-a", Name: "team-a1"}
+	other := model.Agent{Idx: 2, Team: "team-b", Name: "team-b1"}
+	metrics.HandleEvent(model.GameStartedEvent{EventHeader: header, Agents: []model.Agent{agent, {Idx: 3, Team: "team-a", Name: "team-a2"}, other}})
+	metrics.HandleEvent(model.GameStartedEvent{EventHeader: model.EventHeader{GameID: "other", Timestamp: time.Now()}, Agents: []model.Agent{other}})
+	metrics.HandleEvent(model.RequestEndedEvent{EventHeader: header, Agent: agent, Request: model.R_TALK, Duration: 500 * time.Millisecond})
+	metrics.HandleEvent(model.RequestEndedEvent{EventHeader: header, Agent: agent, Request: model.R_TALK, Duration: 3 * time.Second, Error: model.ErrResponseTimeout})
+	agent.HasError = true
+	metrics.HandleEvent(model.RequestEndedEvent{EventHeader: header, Agent: agent, Request: model.R_VOTE, Duration: 10 * time.Second, Error: model.ErrNameResponseTimeout})
+	metrics.HandleEvent(model.RequestEndedEvent{EventHeader: header, Agent: agent, Request: model.R_VOTE, Error: model.ErrInvalidNameResponse})
+
+	output := metrics.Render()
+	expected := []string{
+		"aiwolf_active_games 2",
+		`aiwolf_team_active_games{team="team-a"} 1`,
+		`aiwolf_team_active_games{team="team-b"} 2`,
+		`aiwolf_waiting_room_connections{team="team-a"} 2`,
+		`aiwolf_agent_response_seconds_bucket{request="TALK",le="1"} 1`,
+		`aiwolf_agent_response_seconds_bucket{request="TALK",le="5"} 2`,
+		`aiwolf_agent_response_seconds_bucket{request="TALK",le="+Inf"} 2`,
+		`aiwolf_agent_response_seconds_count{request="VOTE"} 2`,
+		`aiwolf_agent_timeouts_total{request="TALK",team="team-a"} 1`,
+		`aiwolf_agent_timeouts_total{request="VOTE",team="team-a"} 1`,
+		`aiwolf_agent_name_fallbacks_total{request="VOTE",team="team-a"} 2`,
+		`aiwolf_error_agents_total{team="team-a"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Missing metric %q in output:\n%s", line, output)
+		}
+	}
+
+	metrics.HandleEvent(model.GameEndedEvent{EventHeader: header, WinSide: model.T_VILLAGER})
+	output = metrics.Render()
+	for _, line := range []string{"aiwolf_active_games 1", `aiwolf_team_active_games{team="team-b"} 1`, `aiwolf_games_finished_total{win_side="VILLAGER"} 1`} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Missing metric %q in output:\n%s", line, output)
+		}
+	}
+	if strings.Contains(output, `aiwolf_team_active_games{team="team-a"}`) {
+		t.Errorf("Expected team without running games to be removed:\n%s", output)
+	}
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kano-lab/aiwolf-nlp-server/core"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/service"
+)
+
+func TestReplayAndConvertLogs(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	dir := t.TempDir()
+	config.AnalysisService.OutputDir = filepath.Join(dir, "analysis")
+	config.DeprecatedLogService.OutputDir = filepath.Join(dir, "deprecated")
+
+	seer := model.Agent{Idx: 1, Team: "a", Name: "a1", Role: model.R_SEER}
+	villager := model.Agent{Idx: 2, Team: "b", Name: "b1", Role: model.R_VILLAGER}
+	werewolf := model.Agent{Idx: 3, Team: "c", Name: "c1", Role: model.R_WEREWOLF}
+	agents := []model.Agent{seer, villager, werewolf}
+	header := func(day int) model.EventHeader {
+		return model.EventHeader{GameID: "game", Day: day, Timestamp: time.Now()}
+	}
+
+	analysisService := service.NewAnalysisService(*config)
+	analysisService.HandleEvent(model.GameStartedEvent{EventHeader: header(0), Agents: agents})
+	analysisService.HandleEvent(model.DayStartedEvent{EventHeader: header(0)})
+	analysisService.HandleEvent(model.DivinedEvent{EventHeader: header(0), Judge: model.Judge{Agent: seer, Target: werewolf, Result: model.S_WEREWOLF}})
+	analysisService.HandleEvent(model.DayStartedEvent{EventHeader: header(1)})
+	analysisService.HandleEvent(model.TalkSpokenEvent{EventHeader: header(1), Request: model.R_TALK, Talk: model.Talk{Agent: seer, Text: "c1は人狼です"}})
+	analysisService.HandleEvent(model.VoteCastEvent{EventHeader: header(1), Request: model.R_VOTE, Vote: model.Vote{Agent: seer, Target: werewolf}, Decision: model.D_SERVER_SUBSTITUTED})
+	analysisService.HandleEvent(model.ExecutedEvent{EventHeader: header(1), Agent: werewolf})
+	analysisService.HandleEvent(model.GameEndedEvent{EventHeader: header(1), WinSide: model.T_VILLAGER})
+
+	var replay strings.Builder
+	if err := core.Replay(filepath.Join(config.AnalysisService.OutputDir, "game.json"), &replay, 0); err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	for _, line := range []string{"[占い] Agent[01] → Agent[03]: WEREWOLF", "Agent[01]: c1は人狼です", "[投票] Agent[01] → Agent[03] (サーバによる代替)", "Agent[03] が追放されました", "勝利陣営: VILLAGER"} {
+		if !strings.Contains(replay.String(), line) {
+			t.Errorf("Replay does not contain %q:\n%s", line, replay.String())
+		}
+	}
+
+	count, err := core.ConvertLogs(*config, config.AnalysisService.OutputDir)
+	if err != nil || count != 1 {
+		t.Fatalf("Failed to convert logs: %d %v", count, err)
+	}
+	filePaths, _ := filepath.Glob(filepath.Join(config.DeprecatedLogService.OutputDir, "*.log"))
+	if len(filePaths) != 1 {
+		t.Fatalf("Expected 1 converted log, got %v", filePaths)
+	}
+	data, _ := os.ReadFile(filePaths[0])
+	expected := []string{
+		"0,status,1,SEER,ALIVE,a1",
+		"0,divine,1,3,WEREWOLF",
+		"1,talk,0,0,1,c1は人狼です",
+		"1,vote,1,3",
+		"1,execute,3,WEREWOLF",
+		"1,status,3,WEREWOLF,DEAD,c1",
+		"1,result,2,0,VILLAGER",
+	}
+	for _, line := range expected {
+		if !strings.Contains(string(data), line) {
+			t.Errorf("Converted log does not contain %q:\n%s", line, data)
+		}
+	}
+}
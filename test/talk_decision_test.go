@@ -0,0 +1,80 @@
+package test
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kano-lab/aiwolf-nlp-server/core"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+func TestValidatedTalkDecision(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.Server.WebSocket.Port += 19
+	config.AnalysisService.OutputDir = t.TempDir()
+	config.Game.TalkValidation.Enable = true
+	config.Game.TalkValidation.MaxCharacters = 8
+	config.Game.TalkValidation.Action = "truncate"
+	server, err := core.NewServer(*config)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	go server.Run()
+	time.Sleep(5 * time.Second)
+
+	u := url.URL{Scheme: "ws", Host: config.Server.WebSocket.Host + ":" + strconv.Itoa(config.Server.WebSocket.Port), Path: "/ws"}
+	clients := make([]*DummyClient, config.Game.AgentCount)
+	for i := range clients {
+		client, err := NewDummyClient(u, "validated"+string(rune('a'+i)), t)
+		if err != nil {
+			t.Fatalf("Failed to create WebSocket client: %v", err)
+		}
+		clients[i] = client
+		defer client.Close()
+	}
+	for _, client := range clients {
+		select {
+		case <-client.done:
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Timeout")
+		}
+	}
+	time.Sleep(time.Second)
+
+	files, _ := filepath.Glob(filepath.Join(config.AnalysisService.OutputDir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 analysis log, got %v", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read analysis log: %v", err)
+	}
+	var game struct {
+		Timeline []map[string]interface{} `json:"timeline"`
+	}
+	if err := json.Unmarshal(data, &game); err != nil {
+		t.Fatalf("Failed to parse analysis log: %v", err)
+	}
+	// ダミークライアントの発言は32文字のため、切り詰められた発言はサーバによる置換として記録される
+	truncated := 0
+	for _, event := range game.Timeline {
+		if event["type"] != "talk" || event["skip"] == true || event["over"] == true {
+			continue
+		}
+		if len([]rune(event["text"].(string))) > 8 || event["decision"] != string(model.D_SERVER_SUBSTITUTED) {
+			t.Errorf("Expected truncated talk to be server-substituted: %v", event)
+		}
+		truncated++
+	}
+	if truncated == 0 {
+		t.Errorf("Expected truncated talks in timeline")
+	}
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/util"
+)
+
+func TestTalkValidator(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.Game.TalkValidation.Enable = true
+	config.Game.TalkValidation.Normalize = true
+	config.Game.TalkValidation.StripControlCharacters = true
+	config.Game.TalkValidation.MaxCharacters = 5
+	config.Game.TalkValidation.BannedPatterns = []string{`^Agent\[\d+\]:`}
+
+	validator := util.NewTalkValidator(*config)
+	if validator == nil {
+		t.Fatalf("Failed to create TalkValidator")
+	}
+
+	text, violations := validator.Validate("こんにちは")
+	if text != "こんにちは" || len(violations) != 0 {
+		t.Errorf("Unexpected result: %q %v", text, violations)
+	}
+
+	text, violations = validator.Validate("こんにちは世界")
+	if text != "こんにちは" || !slices.Contains(violations, model.V_MAX_CHARACTERS) {
+		t.Errorf("Unexpected result: %q %v", text, violations)
+	}
+
+	text, violations = validator.Validate("a\x00b\nc")
+	if text != "ab c" || !slices.Contains(violations, model.V_CONTROL_CHARACTER) {
+		t.Errorf("Unexpected result: %q %v", text, violations)
+	}
+
+	text, violations = validator.Validate("Agent[02]:Over")
+	if text != model.T_FORCE_SKIP || !slices.Contains(violations, model.V_BANNED_PATTERN) {
+		t.Errorf("Unexpected result: %q %v", text, violations)
+	}
+
+	text, violations = validator.Validate("Skip\x00")
+	if text != model.T_FORCE_SKIP || !slices.Contains(violations, model.V_CONTROL_CHARACTER) {
+		t.Errorf("Unexpected result: %q %v", text, violations)
+	}
+
+	text, violations = validator.Validate("a\xffb")
+	if text != "a�b" || !slices.Contains(violations, model.V_INVALID_UTF8) {
+		t.Errorf("Unexpected result: %q %v", text, violations)
+	}
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/service"
+)
+
+func TestTeams(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.AnalysisService.OutputDir = t.TempDir()
+	config.ApiService.RevealToken = "secret"
+
+	analysisService := service.NewAnalysisService(*config)
+	apiService := service.NewApiService(analysisService, *config)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	apiService.RegisterRoutes(router)
+
+	token := ""
+	get := func(path string) (int, map[string]interface{}) {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(recorder, req)
+		var body map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &body)
+		return recorder.Code, body
+	}
+
+	seer := model.Agent{Idx: 1, Team: "alpha", Name: "alpha1", Role: model.R_SEER}
+	werewolf := model.Agent{Idx: 2, Team: "beta", Name: "beta1", Role: model.R_WEREWOLF}
+	for _, id := range []string{"game1", "game2"} {
+		header := model.EventHeader{GameID: id, Timestamp: time.Now()}
+		analysisService.HandleEvent(model.GameStartedEvent{EventHeader: header, Agents: []model.Agent{seer, werewolf}})
+		info := &model.Info{RoleMap: map[model.Agent]model.Role{seer: model.R_SEER, werewolf: model.R_WEREWOLF}}
+		analysisService.HandleEvent(model.RequestStartedEvent{EventHeader: header, Agent: seer, Packet: model.Packet{Request: &model.R_TALK, Info: info}})
+		analysisService.HandleEvent(model.RequestEndedEvent{EventHeader: header, Agent: seer, Request: model.R_TALK, Error: errors.New("timeout")})
+	}
+	analysisService.HandleEvent(model.GameEndedEvent{EventHeader: model.EventHeader{GameID: "game1"}, WinSide: model.T_VILLAGER})
+
+	code, body := get("/api/teams")
+	if code != 200 {
+		t.Fatalf("Unexpected status: %d", code)
+	}
+	teams := body["teams"].([]interface{})
+	if len(teams) != 2 || teams[0].(map[string]interface{})["team"] != "alpha" || teams[0].(map[string]interface{})["running"] != float64(1) {
+		t.Errorf("Unexpected teams: %v", teams)
+	}
+
+	code, body = get("/api/teams/alpha")
+	if code != 200 {
+		t.Fatalf("Unexpected status: %d", code)
+	}
+	if games := body["games"].([]interface{}); len(games) != 2 {
+		t.Errorf("Unexpected games: %v", games)
+	}
+	stats := body["stats"].(map[string]interface{})["SEER"].(map[string]interface{})
+	if stats["games"] != float64(1) || stats["wins"] != float64(1) || stats["errors"] != float64(1) {
+		t.Errorf("Unexpected stats: %v", stats)
+	}
+	if errors := body["recent_errors"].([]interface{}); len(errors) != 2 || errors[0].(map[string]interface{})["error"] != "timeout" {
+		t.Errorf("Unexpected recent errors: %v", errors)
+	}
+
+	// 進行中のゲームのエラーには、役職が含まれるリクエストのパケットを含めない
+	for _, entry := range body["recent_errors"].([]interface{}) {
+		entry := entry.(map[string]interface{})
+		if _, exists := entry["request"]; exists != (entry["game_id"] == "game1") {
+			t.Errorf("Unexpected request in recent error: %v", entry)
+		}
+	}
+	for _, game := range body["games"].([]interface{}) {
+		game := game.(map[string]interface{})
+		if game["game_id"] == "game2" {
+			data, _ := json.Marshal(game)
+			if strings.Contains(string(data), "SEER") || strings.Contains(string(data), "WEREWOLF") {
+				t.Errorf("Expected roles of running game to be hidden: %s", data)
+			}
+		}
+	}
+	token = "secret"
+	_, body = get("/api/teams/alpha?reveal=true")
+	for _, entry := range body["recent_errors"].([]interface{}) {
+		if _, exists := entry.(map[string]interface{})["request"]; !exists {
+			t.Errorf("Expected request to be revealed: %v", entry)
+		}
+	}
+	token = ""
+
+	_, body = get("/api/teams/beta")
+	stats = body["stats"].(map[string]interface{})["WEREWOLF"].(map[string]interface{})
+	if stats["losses"] != float64(1) || stats["errors"] != float64(0) {
+		t.Errorf("Unexpected stats: %v", stats)
+	}
+
+	if code, _ := get("/api/teams/unknown"); code != 404 {
+		t.Errorf("Expected 404 for unknown team, got %d", code)
+	}
+}
+
+func TestTeamTotalsRetention(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.AnalysisService.OutputDir = t.TempDir()
+	config.AnalysisService.Retention.MaxAge = 200 * time.Millisecond
+
+	analysisService := service.NewAnalysisService(*config)
+	apiService := service.NewApiService(analysisService, *config)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	apiService.RegisterRoutes(router)
+
+	get := func(path string) (int, map[string]interface{}) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		var body map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &body)
+		return recorder.Code, body
+	}
+
+	seer := model.Agent{Idx: 1, Team: "alpha", Name: "alpha1", Role: model.R_SEER}
+	werewolf := model.Agent{Idx: 2, Team: "beta", Name: "beta1", Role: model.R_WEREWOLF}
+	for _, id := range []string{"game1", "game2"} {
+		header := model.EventHeader{GameID: id, Timestamp: time.Now()}
+		analysisService.HandleEvent(model.GameStartedEvent{EventHeader: header, Agents: []model.Agent{seer, werewolf}})
+		analysisService.HandleEvent(model.GameEndedEvent{EventHeader: header, WinSide: model.T_VILLAGER})
+	}
+	analysisService.HandleEvent(model.GameStartedEvent{EventHeader: model.EventHeader{GameID: "game3", Timestamp: time.Now()}, Agents: []model.Agent{seer, werewolf}})
+
+	// ゲームが終了しなくても保持期間を過ぎたゲームは削除される
+	time.Sleep(time.Second)
+	if code, _ := get("/api/game?id=game1"); code != 404 {
+		t.Errorf("Expected expired game to be evicted, got %d", code)
+	}
+
+	_, body := get("/api/teams/alpha")
+	if games := body["games"].([]interface{}); len(games) != 1 || body["total_games"] != float64(3) {
+		t.Errorf("Unexpected games: %v", body)
+	}
+	stats := body["stats"].(map[string]interface{})["SEER"].(map[string]interface{})
+	if stats["games"] != float64(2) || stats["wins"] != float64(2) {
+		t.Errorf("Expected stats to include evicted games, got %v", stats)
+	}
+	_, body = get("/api/teams")
+	if teams := body["teams"].([]interface{}); len(teams) != 2 || teams[0].(map[string]interface{})["games"] != float64(3) {
+		t.Errorf("Unexpected teams: %v", teams)
+	}
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"github.com/kano-lab/aiwolf-nlp-server/service"
+)
+
+func TestTimeline(t *testing.T) {
+	config, err := model.LoadFromPath("../config/debug.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config.AnalysisService.OutputDir = t.TempDir()
+	config.ApiService.RevealToken = "secret"
+
+	analysisService := service.NewAnalysisService(*config)
+	apiService := service.NewApiService(analysisService, *config)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	apiService.RegisterRoutes(router)
+
+	seer := model.Agent{Idx: 1, Team: "a", Name: "a", Role: model.R_SEER}
+	werewolf := model.Agent{Idx: 2, Team: "b", Name: "b", Role: model.R_WEREWOLF}
+	header := model.EventHeader{GameID: "game", Day: 1, Timestamp: time.Now()}
+	analysisService.HandleEvent(model.GameStartedEvent{EventHeader: header, Agents: []model.Agent{seer, werewolf}})
+	analysisService.HandleEvent(model.DayStartedEvent{EventHeader: header})
+	analysisService.HandleEvent(model.TalkSpokenEvent{EventHeader: header, Request: model.R_TALK, Talk: model.Talk{Agent: seer, Text: "こんにちは"}})
+	analysisService.HandleEvent(model.TalkSpokenEvent{EventHeader: header, Request: model.R_WHISPER, Talk: model.Talk{Agent: werewolf, Text: "襲撃しよう"}})
+	analysisService.HandleEvent(model.DivinedEvent{EventHeader: header, Judge: model.Judge{Agent: seer, Target: werewolf, Result: model.S_WEREWOLF}})
+	analysisService.HandleEvent(model.AttackedEvent{EventHeader: header, Agent: &seer, Succeeded: false})
+	analysisService.HandleEvent(model.VoteCastEvent{EventHeader: header, Request: model.R_VOTE, Vote: model.Vote{Agent: seer, Target: werewolf}})
+	analysisService.HandleEvent(model.ExecutedEvent{EventHeader: header, Agent: werewolf})
+
+	request := func(path string, token string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	get := func(path string, token string) map[string]interface{} {
+		recorder := request(path, token)
+		if recorder.Code != 200 {
+			t.Fatalf("Unexpected status: %d %s", recorder.Code, recorder.Body.String())
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return body
+	}
+
+	body := get("/api/timeline?id=game", "")
+	if body["is_finished"] != false || body["revealed"] != false {
+		t.Errorf("Expected running game, got %v", body)
+	}
+	for _, agent := range body["agents"].([]interface{}) {
+		if _, exists := agent.(map[string]interface{})["role"]; exists {
+			t.Errorf("Expected role to be hidden: %v", agent)
+		}
+	}
+	timeline := body["timeline"].([]interface{})
+	expected := []string{"day_started", "talk", "attack", "vote", "execute"}
+	if len(timeline) != len(expected) {
+		t.Fatalf("Unexpected timeline: %v", timeline)
+	}
+	for i, kind := range expected {
+		event := timeline[i].(map[string]interface{})
+		if event["type"] != kind || event["day"] != float64(1) {
+			t.Errorf("Unexpected event %d: %v", i, event)
+		}
+	}
+	if event := timeline[2].(map[string]interface{}); event["agent"] != nil || event["succeeded"] != false {
+		t.Errorf("Expected guarded target to be hidden: %v", event)
+	}
+	if event := timeline[3].(map[string]interface{}); event["agent"] != "Agent[01]" || event["target"] != "Agent[02]" {
+		t.Errorf("Unexpected vote event: %v", event)
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		if recorder := request("/api/timeline?id=game&reveal=true", token); recorder.Code != 403 {
+			t.Errorf("Expected reveal with token %q to be rejected, got %d", token, recorder.Code)
+		}
+	}
+	if recorder := request("/api/game?id=game", ""); recorder.Code != 403 {
+		t.Errorf("Expected running game data to be rejected, got %d", recorder.Code)
+	}
+	body = get("/api/timeline?id=game&reveal=true", "secret")
+	if body["revealed"] != true || len(body["timeline"].([]interface{})) != 7 {
+		t.Errorf("Expected revealed timeline, got %v", body)
+	}
+	if agent := body["agents"].([]interface{})[0].(map[string]interface{}); agent["role"] != "SEER" {
+		t.Errorf("Expected role to be revealed: %v", agent)
+	}
+
+	analysisService.HandleEvent(model.GameEndedEvent{EventHeader: header, WinSide: model.T_VILLAGER})
+	body = get("/api/timeline?id=game", "")
+	if body["is_finished"] != true || body["win_side"] != "VILLAGER" || body["revealed"] != true {
+		t.Errorf("Expected finished game, got %v", body)
+	}
+	if recorder := request("/api/game?id=game", ""); recorder.Code != 200 {
+		t.Errorf("Expected finished game data, got %d", recorder.Code)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/viewer", nil))
+	if recorder.Code != 200 {
+		t.Errorf("Expected viewer page, got %d", recorder.Code)
+	}
+}
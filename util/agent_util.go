@@ -21,12 +21,17 @@ func FilterAgents(agents []*model.Agent, filter func(*model.Agent) bool) []*mode
 	return filtered
 }
 
-func FindAgentByName(agents []*model.Agent, name string) *model.Agent {
+// matchName が true の場合は、一致するエージェントがなければ元の名前でも検索する
+// ペルソナを有効にした場合は元の名前を伏せるため、名前での検索は行わない
+func FindAgentByName(agents []*model.Agent, name string, matchName bool) *model.Agent {
 	for _, agent := range agents {
 		if agent.String() == name {
 			return agent
 		}
 	}
+	if !matchName {
+		return nil
+	}
 	for _, agent := range agents {
 		if agent.Name == name {
 			slog.Warn("対象エージェントを名前で検索しました", "name", name)
@@ -35,3 +40,33 @@ func FindAgentByName(agents []*model.Agent, name string) *model.Agent {
 	}
 	return nil
 }
+
+func AssignPersonas(agents []*model.Agent, pool []model.Persona) {
+	personas := make([]model.Persona, 0)
+	names := make(map[string]struct{})
+	for _, persona := range pool {
+		if persona.Name == "" {
+			slog.Warn("名前が設定されていないペルソナを無視します")
+			continue
+		}
+		if _, exists := names[persona.Name]; exists {
+			slog.Warn("名前が重複しているペルソナを無視します", "name", persona.Name)
+			continue
+		}
+		names[persona.Name] = struct{}{}
+		personas = append(personas, persona)
+	}
+	if len(personas) < len(agents) {
+		slog.Warn("ペルソナの数がエージェント数より少ないため、一部のエージェントにはペルソナを割り当てません", "personas", len(personas), "agents", len(agents))
+	}
+	rand.Shuffle(len(personas), func(i, j int) {
+		personas[i], personas[j] = personas[j], personas[i]
+	})
+	for i, agent := range agents {
+		if i >= len(personas) {
+			break
+		}
+		agent.Persona = &personas[i]
+		slog.Info("ペルソナを割り当てました", "idx", agent.Idx, "agent", agent.String())
+	}
+}
@@ -0,0 +1,343 @@
+package util
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+)
+
+var messageCatalog = []map[model.Language]string{
+	// サーバ
+	{model.L_JA: "サーバを起動しました", model.L_EN: "Server started"},
+	{model.L_JA: "サーバの起動に失敗しました", model.L_EN: "Failed to start server"},
+	{model.L_JA: "シグナルを受信しました", model.L_EN: "Received signal"},
+	{model.L_JA: "ドレイン中のため、新しい接続を受け付けません", model.L_EN: "Rejecting new connection because the server is draining"},
+	{model.L_JA: "ドレインを開始しました", model.L_EN: "Started draining"},
+	{model.L_JA: "待機中のクライアントにサーバの停止を通知しました", model.L_EN: "Notified waiting clients of the shutdown"},
+	{model.L_JA: "進行中のゲームの終了を待機しています", model.L_EN: "Waiting for running game to finish"},
+	{model.L_JA: "ドレインの期限を過ぎたため、進行中のゲームを中断します", model.L_EN: "Aborting running games because the drain deadline has passed"},
+	{model.L_JA: "HTTPサーバの停止に失敗しました", model.L_EN: "Failed to shut down HTTP server"},
+	{model.L_JA: "サーバを停止しました", model.L_EN: "Stopped server"},
+	{model.L_JA: "全てのゲームが終了しました", model.L_EN: "All games have finished"},
+	{model.L_JA: "ゲーム設定の作成に失敗しました", model.L_EN: "Failed to create game settings"},
+	{model.L_JA: "クライアントのアップグレードに失敗しました", model.L_EN: "Failed to upgrade client connection"},
+	{model.L_JA: "クライアントの接続に失敗しました", model.L_EN: "Failed to connect client"},
+	{model.L_JA: "クライアントが接続しました", model.L_EN: "Client connected"},
+	{model.L_JA: "人間のプレイヤーとして接続しました", model.L_EN: "Connected as a human player"},
+	{model.L_JA: "人間のプレイヤーとして参加できないチームのため、エージェントとして扱います", model.L_EN: "Team is not allowed to join as a human player, treating it as an agent"},
+	{model.L_JA: "設定ファイルの読み込みに失敗しました", model.L_EN: "Failed to read config file"},
+	{model.L_JA: "設定ファイルのパースに失敗しました", model.L_EN: "Failed to parse config file"},
+	{model.L_JA: "設定ファイルの検証に失敗しました", model.L_EN: "Failed to validate config file"},
+	{model.L_JA: "設定を上書きしました", model.L_EN: "Overrode config value"},
+	{model.L_JA: "設定を再読み込みしました", model.L_EN: "Reloaded config"},
+	{model.L_JA: "設定の再読み込みに失敗しました", model.L_EN: "Failed to reload config"},
+	{model.L_JA: "設定の再読み込みが有効ではありません", model.L_EN: "config reload is not available"},
+	{model.L_JA: "上書きは key.path=value の形式で指定してください", model.L_EN: "overrides must be in the form key.path=value"},
+	{model.L_JA: "対応する役職の人数がありません", model.L_EN: "No role distribution for the agent count"},
+
+	// 待機部屋
+	{model.L_JA: "切断された接続を待機部屋から削除しました", model.L_EN: "Removed closed connection from waiting room"},
+	{model.L_JA: "新しいクライアントが待機部屋に追加されました", model.L_EN: "New client added to waiting room"},
+	{model.L_JA: "待機部屋からの接続の取得に失敗しました", model.L_EN: "Failed to get connections from waiting room"},
+	{model.L_JA: "待機部屋内の接続が不足しています", model.L_EN: "Not enough connections in waiting room"},
+	{model.L_JA: "マッチの接続を取得しました", model.L_EN: "Got connections for match"},
+	{model.L_JA: "スケジュールされたマッチがありません", model.L_EN: "No scheduled matches"},
+	{model.L_JA: "スケジュールされたマッチ内に不足しているチームがあります", model.L_EN: "Some teams in scheduled matches are missing"},
+	{model.L_JA: "スケジュールされたマッチの接続を取得しました", model.L_EN: "Got connections for scheduled match"},
+	{model.L_JA: "待機部屋から接続を削除しました", model.L_EN: "Removed connection from waiting room"},
+	{model.L_JA: "待機部屋に接続が見つかりません", model.L_EN: "connection not found in waiting room"},
+	{model.L_JA: "待機部屋内の接続で強制的にマッチを作成しました", model.L_EN: "Forced a match with connections in waiting room"},
+
+	// マッチオプティマイザ
+	{model.L_JA: "マッチオプティマイザの作成に失敗しました", model.L_EN: "Failed to create match optimizer"},
+	{model.L_JA: "マッチオプティマイザの読み込みに失敗しました", model.L_EN: "Failed to read match optimizer"},
+	{model.L_JA: "マッチオプティマイザのパースに失敗しました", model.L_EN: "Failed to parse match optimizer"},
+	{model.L_JA: "マッチオプティマイザを作成します", model.L_EN: "Creating match optimizer"},
+	{model.L_JA: "マッチオプティマイザを初期化します", model.L_EN: "Initializing match optimizer"},
+	{model.L_JA: "スケジュールされたマッチがないため、新たに追加します", model.L_EN: "Adding new matches because no scheduled matches remain"},
+	{model.L_JA: "チームが既に登録されています", model.L_EN: "Team is already registered"},
+	{model.L_JA: "チーム数が上限に達しているため追加できません", model.L_EN: "Cannot add team because the team count limit has been reached"},
+	{model.L_JA: "チームを追加しました", model.L_EN: "Added team"},
+	{model.L_JA: "各役職の理論値を計算しました", model.L_EN: "Calculated theoretical value for each role"},
+	{model.L_JA: "マッチング最適化を開始します", model.L_EN: "Starting match optimization"},
+	{model.L_JA: "より良い解が見つかりました", model.L_EN: "Found a better solution"},
+	{model.L_JA: "最良の解を採用します", model.L_EN: "Adopting the best solution"},
+	{model.L_JA: "最適なマッチングが見つかりませんでした", model.L_EN: "No optimal matching found"},
+	{model.L_JA: "スケジュールされたマッチから削除しました", model.L_EN: "Removed from scheduled matches"},
+	{model.L_JA: "マッチ履歴を追加しました", model.L_EN: "Added match history"},
+	{model.L_JA: "スケジュールされたマッチの重みを設定しました", model.L_EN: "Set weight of scheduled match"},
+	{model.L_JA: "スケジュールされたマッチが見つかりませんでした", model.L_EN: "Scheduled match not found"},
+	{model.L_JA: "スケジュールされたマッチを追加しました", model.L_EN: "Added scheduled match"},
+	{model.L_JA: "マッチの役職の人数が一致しません", model.L_EN: "Role counts of the match do not match"},
+	{model.L_JA: "マッチに登録されていないチームが含まれています", model.L_EN: "Match contains an unregistered team"},
+	{model.L_JA: "マッチを再スケジュールしました", model.L_EN: "Rescheduled match"},
+
+	// コマンド
+	{model.L_JA: "-s と -d を指定してください", model.L_EN: "both -s and -d are required"},
+	{model.L_JA: "ログファイルを1つ指定してください", model.L_EN: "specify exactly one log file"},
+	{model.L_JA: "設定ファイルに問題があります", model.L_EN: "config file has problems"},
+
+	// 解析
+	{model.L_JA: "マッチオプティマイザの統計データを分析します", model.L_EN: "Analyzing match optimizer statistics"},
+	{model.L_JA: "ログサービスの統計データを分析します", model.L_EN: "Analyzing log service statistics"},
+	{model.L_JA: "分析サービスの統計データを分析します", model.L_EN: "Analyzing analysis service statistics"},
+	{model.L_JA: "ファイルのパースに失敗しました", model.L_EN: "Failed to parse file"},
+	{model.L_JA: "レポートの出力に失敗しました", model.L_EN: "Failed to write report"},
+	{model.L_JA: "レポートを出力しました", model.L_EN: "Wrote report"},
+	{model.L_JA: "ファイルの取得に失敗しました", model.L_EN: "Failed to get files"},
+	{model.L_JA: "ファイルの読み込みに失敗しました", model.L_EN: "Failed to read file"},
+	{model.L_JA: "役職が取得できませんでした", model.L_EN: "Could not get roles"},
+	{model.L_JA: "結果が取得できませんでした", model.L_EN: "Could not get result"},
+	{model.L_JA: "重複したマッチを削除しました", model.L_EN: "Removed duplicate match"},
+	{model.L_JA: "ゲームログにゲームIDもしくはエージェントが含まれていません", model.L_EN: "game log does not contain a game ID or agents"},
+
+	// エージェント
+	{model.L_JA: "プロトコルエラー: リクエストIDが一致しないレスポンスを破棄しました", model.L_EN: "Protocol error: discarded response with a mismatched request ID"},
+	{model.L_JA: "プロトコルエラー: 対応するリクエストのないレスポンスを破棄しました", model.L_EN: "Protocol error: discarded response without a matching request"},
+	{model.L_JA: "プロトコルエラー: タイムアウトしたリクエストへの遅れたレスポンスを破棄しました", model.L_EN: "Protocol error: discarded late response to a timed-out request"},
+	{model.L_JA: "接続が切断されているため、リクエストを送信できません", model.L_EN: "Cannot send request because the connection is closed"},
+	{model.L_JA: "受信バッファが一杯のため、メッセージを破棄しました", model.L_EN: "Discarded message because the receive buffer is full"},
+	{model.L_JA: "Pingの送信に失敗しました", model.L_EN: "Failed to send ping"},
+	{model.L_JA: "エージェントを作成しました", model.L_EN: "Created agent"},
+	{model.L_JA: "エージェントの作成に失敗しました", model.L_EN: "Failed to create agent"},
+	{model.L_JA: "エージェントをクローズしました", model.L_EN: "Closed agent"},
+	{model.L_JA: "エージェントにエラーが発生しているため、リクエストを送信できません", model.L_EN: "Cannot send request because the agent has an error"},
+	{model.L_JA: "パケットの作成に失敗しました", model.L_EN: "Failed to create packet"},
+	{model.L_JA: "パケットの送信に失敗しました", model.L_EN: "Failed to send packet"},
+	{model.L_JA: "パケットを送信しました", model.L_EN: "Sent packet"},
+	{model.L_JA: "レスポンスを受信しました", model.L_EN: "Received response"},
+	{model.L_JA: "接続が閉じられました", model.L_EN: "Connection closed"},
+	{model.L_JA: "レスポンスの受信に失敗したため、NAMEリクエストを送信します", model.L_EN: "Sending NAME request because receiving the response failed"},
+	{model.L_JA: "レスポンスの受信がタイムアウトしたため、NAMEリクエストを送信します", model.L_EN: "Sending NAME request because receiving the response timed out"},
+	{model.L_JA: "NAMEパケットの作成に失敗しました", model.L_EN: "Failed to create NAME packet"},
+	{model.L_JA: "NAMEパケットの送信に失敗しました", model.L_EN: "Failed to send NAME packet"},
+	{model.L_JA: "NAMEパケットを送信しました", model.L_EN: "Sent NAME packet"},
+	{model.L_JA: "NAMEリクエストの受信に失敗しました", model.L_EN: "Failed to receive NAME request"},
+	{model.L_JA: "NAMEリクエストのレスポンスを受信しました", model.L_EN: "Received response to NAME request"},
+	{model.L_JA: "NAMEリクエストのレスポンス受信に失敗しました", model.L_EN: "Failed to receive response to NAME request"},
+	{model.L_JA: "NAMEリクエストのレスポンス受信がタイムアウトしました", model.L_EN: "Receiving response to NAME request timed out"},
+	{model.L_JA: "ゲームが中断されたため、リクエストを取り消しました", model.L_EN: "Canceled the request because the game was aborted"},
+	{model.L_JA: "不正なNAMEリクエストのレスポンスを受信しました", model.L_EN: "Received invalid response to NAME request"},
+	{model.L_JA: "リクエストのレスポンス受信がタイムアウトしました", model.L_EN: "Receiving response to request timed out"},
+	{model.L_JA: "対象エージェントを名前で検索しました", model.L_EN: "Searched target agent by name"},
+	{model.L_JA: "名前が設定されていないペルソナを無視します", model.L_EN: "Ignoring persona without a name"},
+	{model.L_JA: "名前が重複しているペルソナを無視します", model.L_EN: "Ignoring persona with a duplicate name"},
+	{model.L_JA: "ペルソナの数がエージェント数より少ないため、一部のエージェントにはペルソナを割り当てません", model.L_EN: "Some agents get no persona because there are fewer personas than agents"},
+	{model.L_JA: "ペルソナを割り当てました", model.L_EN: "Assigned persona"},
+
+	// ゲーム
+	{model.L_JA: "ゲームを作成しました", model.L_EN: "Created game"},
+	{model.L_JA: "ゲームを開始します", model.L_EN: "Starting game"},
+	{model.L_JA: "ゲームが終了しました", model.L_EN: "Game finished"},
+	{model.L_JA: "エラーが多発したため、ゲームを終了します", model.L_EN: "Finishing game because of too many errors"},
+	{model.L_JA: "日付が進みました", model.L_EN: "Day advanced"},
+	{model.L_JA: "昼を開始します", model.L_EN: "Starting day"},
+	{model.L_JA: "昼を終了します", model.L_EN: "Finishing day"},
+	{model.L_JA: "夜を開始します", model.L_EN: "Starting night"},
+	{model.L_JA: "夜を終了します", model.L_EN: "Finishing night"},
+	{model.L_JA: "一致するリクエストがありません", model.L_EN: "No matching request"},
+	{model.L_JA: "対象エージェントが見つかりません", model.L_EN: "Target agent not found"},
+	{model.L_JA: "対象エージェントを受信しました", model.L_EN: "Received target agent"},
+	{model.L_JA: "追放フェーズを開始します", model.L_EN: "Starting execution phase"},
+	{model.L_JA: "追放フェーズを終了します", model.L_EN: "Finishing execution phase"},
+	{model.L_JA: "追放結果を設定しました", model.L_EN: "Set execution result"},
+	{model.L_JA: "追放対象がいないため、追放結果を設定しません", model.L_EN: "No execution result because there is no target"},
+	{model.L_JA: "霊能結果を設定しました", model.L_EN: "Set medium result"},
+	{model.L_JA: "襲撃フェーズを開始します", model.L_EN: "Starting attack phase"},
+	{model.L_JA: "襲撃フェーズを終了します", model.L_EN: "Finishing attack phase"},
+	{model.L_JA: "襲撃結果を設定しました", model.L_EN: "Set attack result"},
+	{model.L_JA: "護衛されたため、襲撃結果を設定しません", model.L_EN: "No attack result because the target was guarded"},
+	{model.L_JA: "襲撃対象がいないため、襲撃結果を設定しません", model.L_EN: "No attack result because there is no target"},
+	{model.L_JA: "占いフェーズを開始します", model.L_EN: "Starting divine phase"},
+	{model.L_JA: "占いフェーズを終了します", model.L_EN: "Finishing divine phase"},
+	{model.L_JA: "占いアクションを開始します", model.L_EN: "Starting divine action"},
+	{model.L_JA: "占い対象が見つからなかったため、占い結果を設定しません", model.L_EN: "No divine result because the target was not found"},
+	{model.L_JA: "占い対象が死亡しているため、占い結果を設定しません", model.L_EN: "No divine result because the target is dead"},
+	{model.L_JA: "占い対象が自分自身であるため、占い結果を設定しません", model.L_EN: "No divine result because the target is the agent itself"},
+	{model.L_JA: "占い結果を設定しました", model.L_EN: "Set divine result"},
+	{model.L_JA: "護衛フェーズを開始します", model.L_EN: "Starting guard phase"},
+	{model.L_JA: "護衛アクションを実行します", model.L_EN: "Executing guard action"},
+	{model.L_JA: "護衛対象が見つからなかったため、護衛対象を設定しません", model.L_EN: "No guard target because the target was not found"},
+	{model.L_JA: "護衛対象が死亡しているため、護衛対象を設定しません", model.L_EN: "No guard target because the target is dead"},
+	{model.L_JA: "護衛対象が自分自身であるため、護衛対象を設定しません", model.L_EN: "No guard target because the target is the agent itself"},
+	{model.L_JA: "護衛対象を設定しました", model.L_EN: "Set guard target"},
+	{model.L_JA: "投票アクションを開始します", model.L_EN: "Starting vote action"},
+	{model.L_JA: "襲撃投票アクションを開始します", model.L_EN: "Starting attack vote action"},
+	{model.L_JA: "投票対象が死亡しているため、投票を無視します", model.L_EN: "Ignoring vote because the target is dead"},
+	{model.L_JA: "投票を受信しました", model.L_EN: "Received vote"},
+	{model.L_JA: "同票のため、タイブレークを行います", model.L_EN: "Breaking tie"},
+	{model.L_JA: "決選投票でも同票となったため、ランダムに選択します", model.L_EN: "Selecting randomly because the runoff vote also tied"},
+	{model.L_JA: "タイブレークの結果を設定しました", model.L_EN: "Set tie-break result"},
+	{model.L_JA: "投票宣言アクションを開始します", model.L_EN: "Starting vote declaration action"},
+	{model.L_JA: "同票のため、決選投票を行います", model.L_EN: "Holding runoff vote because of a tie"},
+	{model.L_JA: "投票対象が決選投票の候補ではないため、投票を無視します", model.L_EN: "Ignoring vote because the target is not a runoff candidate"},
+	{model.L_JA: "代わりの行動が棄権であるため、アクションを行いません", model.L_EN: "Taking no action because the fallback is abstain"},
+	{model.L_JA: "代わりの対象がないため、アクションを行いません", model.L_EN: "Taking no action because there is no fallback target"},
+	{model.L_JA: "レスポンスが得られなかったため、代わりの対象を選択しました", model.L_EN: "Selected a fallback target because no response was received"},
+	{model.L_JA: "囁きフェーズを開始します", model.L_EN: "Starting whisper phase"},
+	{model.L_JA: "トークフェーズを開始します", model.L_EN: "Starting talk phase"},
+	{model.L_JA: "エージェント数が2未満のため、通信を行いません", model.L_EN: "Skipping communication because there are fewer than 2 agents"},
+	{model.L_JA: "発言がオーバーであるため、残り発言回数を0にしました", model.L_EN: "Set remaining talk count to 0 because the talk is Over"},
+	{model.L_JA: "発言を受信しました", model.L_EN: "Received talk"},
+	{model.L_JA: "クライアントから強制スキップが指定されたため、発言をスキップに置換しました", model.L_EN: "Replaced talk with Skip because the client sent ForceSkip"},
+	{model.L_JA: "リクエストの送受信に失敗したため、発言をスキップに置換しました", model.L_EN: "Replaced talk with Skip because the request failed"},
+	{model.L_JA: "スキップ回数が上限に達したため、発言をオーバーに置換しました", model.L_EN: "Replaced talk with Over because the skip limit was reached"},
+	{model.L_JA: "発言をスキップしました", model.L_EN: "Skipped talk"},
+	{model.L_JA: "強制スキップが指定されたため、発言をスキップに置換しました", model.L_EN: "Replaced talk with Skip because ForceSkip was specified"},
+	{model.L_JA: "発言がオーバーもしくはスキップではないため、スキップ回数をリセットしました", model.L_EN: "Reset skip count because the talk is neither Over nor Skip"},
+	{model.L_JA: "発言の検証に違反したため、発言を置換しました", model.L_EN: "Replaced talk because it violated validation"},
+	{model.L_JA: "違反時の処理が不正なため、切り詰めを使用します", model.L_EN: "Using truncate because the violation action is invalid"},
+	{model.L_JA: "禁止パターンのコンパイルに失敗したため、無視します", model.L_EN: "Ignoring banned pattern that failed to compile"},
+	{model.L_JA: "発言の言語がゲームの言語と一致しません", model.L_EN: "Talk language does not match the game language"},
+	{model.L_JA: "ゲームの中断が要求されました", model.L_EN: "Game abort requested"},
+	{model.L_JA: "ゲームを一時停止しました", model.L_EN: "Paused game"},
+	{model.L_JA: "ゲームを再開しました", model.L_EN: "Resumed game"},
+	{model.L_JA: "ゲームが中断されたため、ゲームを終了します", model.L_EN: "Finishing game because it was aborted"},
+	{model.L_JA: "ゲームが中断されたため、リクエストを送信しません", model.L_EN: "Not sending request because the game was aborted"},
+	{model.L_JA: "ステップ実行モードを設定しました", model.L_EN: "Set step mode"},
+	{model.L_JA: "ゲームをステップ実行します", model.L_EN: "Stepping game"},
+	{model.L_JA: "フェーズの開始時にゲームを一時停止しました", model.L_EN: "Paused game at the start of a phase"},
+
+	// チェックポイント
+	{model.L_JA: "チェックポイントを読み込みました", model.L_EN: "Loaded checkpoint"},
+	{model.L_JA: "チェックポイントの読み込みに失敗しました", model.L_EN: "Failed to load checkpoint"},
+	{model.L_JA: "チェックポイントを保存しました", model.L_EN: "Saved checkpoint"},
+	{model.L_JA: "チェックポイントの保存に失敗しました", model.L_EN: "Failed to save checkpoint"},
+	{model.L_JA: "チェックポイントの削除に失敗しました", model.L_EN: "Failed to remove checkpoint"},
+	{model.L_JA: "チェックポイントからゲームを再開します", model.L_EN: "Resuming game from checkpoint"},
+	{model.L_JA: "チェックポイントからのゲームの再開に失敗しました", model.L_EN: "Failed to resume game from checkpoint"},
+	{model.L_JA: "チェックポイントのゲームを中断しました", model.L_EN: "Aborted checkpointed game"},
+	{model.L_JA: "再開待ちのゲームにエージェントが再接続しました", model.L_EN: "Agent reconnected to a game waiting for resumption"},
+	{model.L_JA: "再接続していないエージェントがあります", model.L_EN: "some agents have not reconnected"},
+	{model.L_JA: "チェックポイントに再開する日のゲームの状態が含まれていません", model.L_EN: "checkpoint does not contain the game status of the day to resume"},
+	{model.L_JA: "チェックポイントのフェーズが不正です", model.L_EN: "Invalid phase in checkpoint"},
+	{model.L_JA: "ゲームの状態に不明なエージェントが含まれています", model.L_EN: "game status contains an unknown agent"},
+
+	// API
+	{model.L_JA: "idが必要です", model.L_EN: "id is required"},
+	{model.L_JA: "ゲームが見つかりません", model.L_EN: "game not found"},
+	{model.L_JA: "ゲームが進行中です", model.L_EN: "game is running"},
+	{model.L_JA: "進行中のゲームの役職を公開する権限がありません", model.L_EN: "reveal is not allowed"},
+	{model.L_JA: "チームが見つかりません", model.L_EN: "team not found"},
+	{model.L_JA: "ゲームデータをメモリから削除しました", model.L_EN: "Evicted game data from memory"},
+	{model.L_JA: "statusが不正です", model.L_EN: "invalid status"},
+	{model.L_JA: "win_sideが不正です", model.L_EN: "invalid win_side"},
+	{model.L_JA: "sinceが不正です", model.L_EN: "invalid since"},
+	{model.L_JA: "untilが不正です", model.L_EN: "invalid until"},
+	{model.L_JA: "orderが不正です", model.L_EN: "invalid order"},
+	{model.L_JA: "limitが不正です", model.L_EN: "invalid limit"},
+	{model.L_JA: "cursorが不正です", model.L_EN: "invalid cursor"},
+
+	// 管理API
+	{model.L_JA: "設定を再読み込みできませんでした", model.L_EN: "failed to reload config"},
+	{model.L_JA: "管理APIのトークンが設定されていないため、管理APIを無効にします", model.L_EN: "Disabling admin API because no token is configured"},
+	{model.L_JA: "管理APIの認証に失敗しました", model.L_EN: "Admin API authentication failed"},
+	{model.L_JA: "管理APIからゲームを強制的に開始しました", model.L_EN: "Force-started game from admin API"},
+	{model.L_JA: "管理APIからドレインが要求されました", model.L_EN: "Drain requested from admin API"},
+	{model.L_JA: "サーバはドレイン中です", model.L_EN: "server is draining"},
+	{model.L_JA: "マッチオプティマイザが有効な場合は、ゲームを強制的に開始できません", model.L_EN: "force start is not available with match optimizer"},
+	{model.L_JA: "管理APIが無効なため、ステップ実行モードのゲームを操作できません", model.L_EN: "Cannot control games in step mode because the admin API is disabled"},
+	{model.L_JA: "認証に失敗しました", model.L_EN: "unauthorized"},
+	{model.L_JA: "ゲームは終了しています", model.L_EN: "game is finished"},
+	{model.L_JA: "マッチオプティマイザが無効です", model.L_EN: "match optimizer is disabled"},
+	{model.L_JA: "リクエストボディが不正です", model.L_EN: "invalid request body"},
+	{model.L_JA: "マッチのインデックスが不正です", model.L_EN: "invalid match index"},
+}
+
+var messageIndex = func() map[string]map[model.Language]string {
+	index := make(map[string]map[model.Language]string)
+	for _, entry := range messageCatalog {
+		for _, message := range entry {
+			index[message] = entry
+		}
+	}
+	return index
+}()
+
+func HasMessage(message string) bool {
+	_, exists := messageIndex[message]
+	return exists
+}
+
+func TranslateMessage(message string, language model.Language) string {
+	if entry, exists := messageIndex[message]; exists {
+		if translated, exists := entry[language]; exists {
+			return translated
+		}
+	}
+	return message
+}
+
+func TranslateForAcceptLanguage(message string, acceptLanguage string) string {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag = strings.TrimSpace(strings.SplitN(tag, ";", 2)[0])
+		if language := model.LanguageFromString(strings.SplitN(tag, "-", 2)[0]); language != "" {
+			return TranslateMessage(message, language)
+		}
+	}
+	return TranslateMessage(message, model.L_EN)
+}
+
+type MessageHandler struct {
+	handler  slog.Handler
+	language model.Language
+}
+
+func NewMessageHandler(handler slog.Handler, language model.Language) *MessageHandler {
+	return &MessageHandler{
+		handler:  handler,
+		language: language,
+	}
+}
+
+func (h *MessageHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *MessageHandler) Handle(ctx context.Context, record slog.Record) error {
+	translated := slog.NewRecord(record.Time, record.Level, TranslateMessage(record.Message, h.language), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		translated.AddAttrs(h.translateAttr(attr))
+		return true
+	})
+	return h.handler.Handle(ctx, translated)
+}
+
+func (h *MessageHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	translated := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		translated[i] = h.translateAttr(attr)
+	}
+	return NewMessageHandler(h.handler.WithAttrs(translated), h.language)
+}
+
+func (h *MessageHandler) WithGroup(name string) slog.Handler {
+	return NewMessageHandler(h.handler.WithGroup(name), h.language)
+}
+
+// 発言などの利用者が送った内容を翻訳しないよう、メッセージとエラーの属性のみを翻訳する
+func (h *MessageHandler) translateAttr(attr slog.Attr) slog.Attr {
+	if attr.Key != slog.MessageKey && attr.Key != "error" {
+		return attr
+	}
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, TranslateMessage(attr.Value.String(), h.language))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, TranslateMessage(err.Error(), h.language))
+		}
+	}
+	return attr
+}
+
+func SetLogLanguage(language model.Language) {
+	// ソースコード上のメッセージは日本語であるため、日本語の場合は既定のロガーをそのまま使用
+	if language == "" || language == model.L_JA {
+		return
+	}
+	slog.SetDefault(slog.New(NewMessageHandler(slog.NewTextHandler(os.Stderr, nil), language)))
+}
@@ -0,0 +1,125 @@
+package util
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kano-lab/aiwolf-nlp-server/model"
+	"golang.org/x/text/unicode/norm"
+)
+
+type TalkValidator struct {
+	maxCharacters          int
+	maxBytes               int
+	normalize              bool
+	stripControlCharacters bool
+	bannedPatterns         []*regexp.Regexp
+	action                 model.ViolationAction
+}
+
+func NewTalkValidator(config model.Config) *TalkValidator {
+	if !config.Game.TalkValidation.Enable {
+		return nil
+	}
+	action := model.ViolationActionFromString(config.Game.TalkValidation.Action)
+	if action == "" {
+		slog.Warn("違反時の処理が不正なため、切り詰めを使用します", "action", config.Game.TalkValidation.Action)
+		action = model.VA_TRUNCATE
+	}
+	validator := &TalkValidator{
+		maxCharacters:          config.Game.TalkValidation.MaxCharacters,
+		maxBytes:               config.Game.TalkValidation.MaxBytes,
+		normalize:              config.Game.TalkValidation.Normalize,
+		stripControlCharacters: config.Game.TalkValidation.StripControlCharacters,
+		bannedPatterns:         make([]*regexp.Regexp, 0),
+		action:                 action,
+	}
+	for _, pattern := range config.Game.TalkValidation.BannedPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			slog.Warn("禁止パターンのコンパイルに失敗したため、無視します", "pattern", pattern, "error", err)
+			continue
+		}
+		validator.bannedPatterns = append(validator.bannedPatterns, re)
+	}
+	return validator
+}
+
+func (tv *TalkValidator) Action() model.ViolationAction {
+	return tv.action
+}
+
+func (tv *TalkValidator) Validate(text string) (string, []model.Violation) {
+	original := text
+	violations := make([]model.Violation, 0)
+	if tv.normalize {
+		if !utf8.ValidString(text) {
+			violations = append(violations, model.V_INVALID_UTF8)
+			text = strings.ToValidUTF8(text, string(utf8.RuneError))
+		}
+		text = norm.NFC.String(text)
+	}
+	if tv.stripControlCharacters {
+		stripped := strings.Map(func(r rune) rune {
+			if !unicode.IsControl(r) {
+				return r
+			}
+			// 改行やタブは空白に置換し、それ以外の制御文字は除去
+			if unicode.IsSpace(r) {
+				return ' '
+			}
+			return -1
+		}, text)
+		if stripped != text {
+			violations = append(violations, model.V_CONTROL_CHARACTER)
+			text = stripped
+		}
+	}
+	for _, re := range tv.bannedPatterns {
+		if re.MatchString(text) {
+			violations = append(violations, model.V_BANNED_PATTERN)
+			text = re.ReplaceAllString(text, "")
+		}
+	}
+	if tv.maxCharacters > 0 && utf8.RuneCountInString(text) > tv.maxCharacters {
+		violations = append(violations, model.V_MAX_CHARACTERS)
+		text = string([]rune(text)[:tv.maxCharacters])
+	}
+	if tv.maxBytes > 0 && len(text) > tv.maxBytes {
+		violations = append(violations, model.V_MAX_BYTES)
+		end := tv.maxBytes
+		for end > 0 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		text = text[:end]
+	}
+	// 検証後の発言がオーバーもしくはスキップと一致する場合は、制御発言として扱われないようにスキップに置換する
+	if text != original && slices.Contains([]string{model.T_OVER, model.T_SKIP, model.T_FORCE_SKIP}, strings.TrimSpace(text)) {
+		text = model.T_FORCE_SKIP
+	}
+	return text, violations
+}
+
+func DetectLanguage(text string) model.Language {
+	var japanese, latin int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han):
+			japanese++
+		case unicode.In(r, unicode.Latin):
+			latin++
+		}
+	}
+	// 日本語の文章には英字が混在することが多いため、日本語の文字が一定割合以上含まれる場合は日本語と判定
+	if japanese > 0 && japanese*5 >= latin {
+		return model.L_JA
+	}
+	if latin > 0 {
+		return model.L_EN
+	}
+	return ""
+}
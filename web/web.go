@@ -0,0 +1,12 @@
+package web
+
+import _ "embed"
+
+//go:embed human.html
+var HumanClient []byte
+
+//go:embed viewer.html
+var Viewer []byte
+
+//go:embed report.html
+var Report string